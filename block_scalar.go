@@ -0,0 +1,129 @@
+package aiyaml
+
+import (
+	"strings"
+)
+
+// blockScalarHeader 块标量头部（| 或 > 及其修饰符）
+type blockScalarHeader struct {
+	folded   bool // > 折叠风格
+	chomping byte // '-' 去除、'+' 保留、0 默认裁剪
+	indent   int  // 显式缩进指示，0表示自动检测
+}
+
+// parseBlockScalarHeader 解析块标量头部，如 |、|-、>+、|2-
+func parseBlockScalarHeader(value string) (blockScalarHeader, bool) {
+	var header blockScalarHeader
+	if value == "" || (value[0] != '|' && value[0] != '>') {
+		return header, false
+	}
+	header.folded = value[0] == '>'
+	rest := value[1:]
+	for ; rest != ""; rest = rest[1:] {
+		ch := rest[0]
+		if (ch == '-' || ch == '+') && header.chomping == 0 {
+			header.chomping = ch
+		} else if ch >= '1' && ch <= '9' && header.indent == 0 {
+			header.indent = int(ch - '0')
+		} else {
+			break
+		}
+	}
+	rest = strings.TrimLeft(rest, " \t")
+	if rest != "" && !strings.HasPrefix(rest, "#") {
+		return header, false
+	}
+	return header, true
+}
+
+// parseBlockScalar 读取块标量内容，parentIndent为所属节点的缩进
+func (lp *lineParser) parseBlockScalar(header blockScalarHeader, parentIndent int) string {
+	contentIndent := -1
+	if header.indent > 0 {
+		contentIndent = parentIndent + header.indent
+		if parentIndent < 0 {
+			// 根节点的缩进为0
+			contentIndent = header.indent
+		}
+	}
+
+	var content []string
+	for lp.pos < len(lp.lines) {
+		l := lp.lines[lp.pos]
+		if strings.TrimSpace(l.raw) == "" {
+			content = append(content, "")
+			lp.pos++
+			continue
+		}
+		if contentIndent < 0 {
+			if l.indent <= parentIndent {
+				break
+			}
+			contentIndent = l.indent
+		}
+		if l.indent < contentIndent {
+			break
+		}
+		content = append(content, trimIndent(l.raw, contentIndent, lp.tabWidth))
+		// 块标量中以#开头的行是内容而不是注释
+		l.comment = ""
+		lp.pos++
+	}
+
+	// 分离末尾空行，按chomping规则处理
+	body := content
+	for len(body) > 0 && body[len(body)-1] == "" {
+		body = body[:len(body)-1]
+	}
+	trailing := len(content) - len(body)
+
+	var sb strings.Builder
+	if header.folded {
+		sb.WriteString(foldBlockLines(body))
+	} else {
+		sb.WriteString(strings.Join(body, "\n"))
+	}
+
+	switch header.chomping {
+	case '-':
+	case '+':
+		if len(body) > 0 {
+			sb.WriteByte('\n')
+		}
+		sb.WriteString(strings.Repeat("\n", trailing))
+	default:
+		if len(body) > 0 {
+			sb.WriteByte('\n')
+		}
+	}
+	return sb.String()
+}
+
+// foldBlockLines 按折叠风格合并块标量行：普通行之间的换行折叠为空格，
+// 空行保留为换行，缩进更深的行保持原样
+func foldBlockLines(lines []string) string {
+	var sb strings.Builder
+	prev := -1
+	for i, line := range lines {
+		if line == "" {
+			sb.WriteByte('\n')
+			continue
+		}
+		if prev >= 0 {
+			switch {
+			case isMoreIndented(line) || isMoreIndented(lines[prev]):
+				sb.WriteByte('\n')
+			case prev == i-1:
+				sb.WriteByte(' ')
+			}
+		}
+		sb.WriteString(line)
+		prev = i
+	}
+	return sb.String()
+}
+
+// isMoreIndented 判断块标量行是否比内容缩进更深
+func isMoreIndented(line string) bool {
+	return strings.HasPrefix(line, " ") || strings.HasPrefix(line, "\t")
+}
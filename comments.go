@@ -0,0 +1,155 @@
+package aiyaml
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Comment 值的注释，每行去除开头的 "#" 和一个空格，多行注释以换行连接
+type Comment struct {
+	// Head 值之前的整行注释
+	Head string
+	// Line 与值的键（或数组项）在同一行的行尾注释
+	Line string
+	// Foot 值之后、缩进比下一个条目更深的整行注释，以及文档末尾的注释
+	Foot string
+}
+
+// IsEmpty 判断是否没有任何注释
+func (c Comment) IsEmpty() bool {
+	return c.Head == "" && c.Line == "" && c.Foot == ""
+}
+
+// commentTarget 可以带尾注释的条目（mapping的键或数组项）及其所在行和缩进
+type commentTarget struct {
+	node   *yaml.Node
+	line   int
+	indent int
+}
+
+// addCommentTarget 登记可以带尾注释的条目
+func (lp *lineParser) addCommentTarget(node *yaml.Node, line, indent int) {
+	if node != nil {
+		lp.targets = append(lp.targets, commentTarget{node: node, line: line, indent: indent})
+	}
+}
+
+// attachFootComments 将当前文档中没有作为头注释的整行注释归属为尾注释：
+// 归属到注释之前最近的、缩进不大于注释的条目，同一行有多个条目时归属到缩进最深的条目
+func (lp *lineParser) attachFootComments() {
+	targets := lp.targets
+	lp.targets = nil
+	sort.SliceStable(targets, func(i, j int) bool {
+		return targets[i].line < targets[j].line
+	})
+	for _, l := range lp.lines {
+		if l.text != "" || l.comment == "" {
+			continue
+		}
+		var target *commentTarget
+		end := sort.Search(len(targets), func(i int) bool { return targets[i].line >= l.num })
+		for i := end - 1; i >= 0; i-- {
+			t := &targets[i]
+			if target != nil && t.line < target.line {
+				break
+			}
+			if t.indent <= l.indent && (target == nil || t.indent > target.indent) {
+				target = t
+			}
+		}
+		if target == nil {
+			continue
+		}
+		target.node.FootComment = joinComments(target.node.FootComment, l.comment)
+		l.comment = ""
+	}
+}
+
+// collectComments 返回所有带注释的值的路径和注释，没有注释时返回nil。
+// 路径由键和数组下标以 "." 连接（如 "settings.timeout"、"steps.0.run"），根节点为空字符串，
+// 多文档时以文档序号开头（如 "1.settings.timeout"）
+func collectComments(roots []*yaml.Node) map[string]Comment {
+	var comments map[string]Comment
+	add := func(path string, nodes ...*yaml.Node) {
+		var c Comment
+		for _, n := range nodes {
+			c.Head = joinComments(c.Head, n.HeadComment)
+			c.Line = joinComments(c.Line, n.LineComment)
+			c.Foot = joinComments(c.Foot, n.FootComment)
+		}
+		if c.IsEmpty() {
+			return
+		}
+		if comments == nil {
+			comments = make(map[string]Comment)
+		}
+		comments[path] = Comment{Head: commentText(c.Head), Line: commentText(c.Line), Foot: commentText(c.Foot)}
+	}
+	for i, root := range roots {
+		if root == nil {
+			continue
+		}
+		path := ""
+		if len(roots) > 1 {
+			path = strconv.Itoa(i)
+		}
+		add(path, root)
+		walkComments(root, path, add)
+	}
+	return comments
+}
+
+// walkComments 深度优先遍历mapping的键值对和数组项，回调路径和带注释的节点（键节点和值节点）
+func walkComments(n *yaml.Node, path string, fn func(path string, nodes ...*yaml.Node)) {
+	switch n.Kind {
+	case yaml.MappingNode:
+		for i := 0; i+1 < len(n.Content); i += 2 {
+			key, value := n.Content[i], n.Content[i+1]
+			child := joinPath(path, mappingKey(key))
+			fn(child, key, value)
+			if value.Kind != yaml.AliasNode {
+				walkComments(value, child, fn)
+			}
+		}
+	case yaml.SequenceNode:
+		for i, item := range n.Content {
+			child := joinPath(path, strconv.Itoa(i))
+			fn(child, item)
+			if item.Kind != yaml.AliasNode {
+				walkComments(item, child, fn)
+			}
+		}
+	}
+}
+
+// joinPath 以 "." 连接路径
+func joinPath(path, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
+
+// joinComments 以换行连接非空的注释
+func joinComments(a, b string) string {
+	if a == "" {
+		return b
+	}
+	if b == "" {
+		return a
+	}
+	return a + "\n" + b
+}
+
+// commentText 去除注释每行开头的 "#" 和一个空格
+func commentText(comment string) string {
+	lines := strings.Split(comment, "\n")
+	for i, line := range lines {
+		line = strings.TrimPrefix(strings.TrimSpace(line), "#")
+		lines[i] = strings.TrimPrefix(line, " ")
+	}
+	return strings.Join(lines, "\n")
+}
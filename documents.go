@@ -0,0 +1,91 @@
+package aiyaml
+
+import (
+	"context"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// DocumentSelector 输入包含多个文档（以 --- 分隔）时LinesToMap返回的文档
+type DocumentSelector int
+
+const (
+	// DocumentMerge 将所有文档合并为一个map，后出现的键覆盖先出现的键（默认，与旧版本行为一致）
+	DocumentMerge DocumentSelector = iota
+	// DocumentFirst 只返回第一个文档
+	DocumentFirst
+	// DocumentLast 只返回最后一个文档
+	DocumentLast
+)
+
+// LinesToDocuments 将yaml代码行按文档标记拆分，每个文档转换为一个map，
+// 任一文档的根节点为sequence或标量时返回ErrRootNotMapping
+func (yp *YAMLParser) LinesToDocuments(ctx context.Context, lines []string) ([]map[string]interface{}, error) {
+	result, err := yp.Parse(ctx, lines)
+	if err != nil {
+		return nil, err
+	}
+	return result.documentMaps()
+}
+
+// parseDocuments 按 --- 和 ... 拆分文档并逐个解析，忽略 %YAML 等指令行和没有内容的文档。
+// 锚点只在所属文档内有效
+func (lp *lineParser) parseDocuments() []*yaml.Node {
+	all := lp.lines
+	var docs []*yaml.Node
+	start := 0
+	flush := func(end int) {
+		lp.lines, lp.pos = all[start:end], 0
+		lp.anchors = make(map[string]*yaml.Node)
+		if lp.peek() != nil {
+			doc := lp.parseDocument()
+			lp.attachFootComments()
+			duplicates := findDuplicateKeys(doc)
+			lp.diagnostics = append(lp.diagnostics, duplicates...)
+			lp.duplicates = append(lp.duplicates, duplicates...)
+			docs = append(docs, doc)
+		}
+	}
+	for i, l := range all {
+		if l.indent > 0 {
+			continue
+		}
+		switch {
+		case isDirective(l.raw):
+			l.text = ""
+		case isDocumentEnd(l.raw):
+			flush(i)
+			start = i + 1
+		case isDocumentStart(l.raw):
+			flush(i)
+			start = i
+			// "--- " 之后的内容作为文档的第一行
+			l.text = strings.TrimLeft(l.text[3:], " \t")
+			l.indent = len(l.raw) - len(strings.TrimLeft(l.raw[3:], " \t"))
+		}
+	}
+	flush(len(all))
+	lp.lines, lp.pos = all, len(all)
+	return docs
+}
+
+// isDocumentStart 判断是否为文档开始标记 ---
+func isDocumentStart(line string) bool {
+	return line == "---" || strings.HasPrefix(line, "--- ") || strings.HasPrefix(line, "---\t")
+}
+
+// isDocumentEnd 判断是否为文档结束标记 ...
+func isDocumentEnd(line string) bool {
+	return strings.TrimRight(line, " \t") == "..." || strings.HasPrefix(line, "... ")
+}
+
+// isDirective 判断是否为 %YAML、%TAG 等指令行
+func isDirective(line string) bool {
+	return strings.HasPrefix(line, "%")
+}
+
+// isDocumentMarker 判断是否为文档标记或指令行
+func isDocumentMarker(line string) bool {
+	return isDocumentStart(line) || isDocumentEnd(line) || isDirective(line)
+}
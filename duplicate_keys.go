@@ -0,0 +1,102 @@
+package aiyaml
+
+import (
+	"fmt"
+
+	"gopkg.in/yaml.v3"
+)
+
+// DuplicateKeyPolicy 同一mapping中出现重复键时的处理方式，无论哪种方式每个重复键都会记录一条警告
+type DuplicateKeyPolicy int
+
+const (
+	// DuplicateKeyLastWins 后出现的值覆盖先前的值（默认）
+	DuplicateKeyLastWins DuplicateKeyPolicy = iota
+	// DuplicateKeyFirstWins 保留第一次出现的值
+	DuplicateKeyFirstWins
+	// DuplicateKeyMerge 两个值都是mapping时递归合并，都是sequence时拼接，其余情况后出现的值覆盖先前的值
+	DuplicateKeyMerge
+	// DuplicateKeyError 返回描述第一个重复键的*ParseError
+	DuplicateKeyError
+)
+
+// findDuplicateKeys 检查节点树中每个mapping的重复键（合并键<<除外），返回包含两处行号的警告
+func findDuplicateKeys(n *yaml.Node) []*ParseError {
+	if n == nil || n.Kind == yaml.AliasNode {
+		return nil
+	}
+	var duplicates []*ParseError
+	if n.Kind == yaml.MappingNode {
+		seen := make(map[string]*yaml.Node, len(n.Content)/2)
+		for i := 0; i+1 < len(n.Content); i += 2 {
+			key := n.Content[i]
+			if isMergeKey(key) {
+				continue
+			}
+			name := mappingKey(key)
+			first, exists := seen[name]
+			if !exists {
+				seen[name] = key
+				continue
+			}
+			duplicates = append(duplicates, &ParseError{
+				Line:    key.Line,
+				Column:  key.Column,
+				Snippet: name,
+				Reason:  fmt.Sprintf("重复的键，首次出现在第%d行", first.Line),
+			})
+		}
+	}
+	for _, child := range n.Content {
+		duplicates = append(duplicates, findDuplicateKeys(child)...)
+	}
+	return duplicates
+}
+
+// setMappingValue 按重复键策略将值写入mapping
+func (d *nodeDecoder) setMappingValue(m *OrderedMap, key string, value interface{}) {
+	existing, exists := m.Get(key)
+	if !exists {
+		m.Set(key, value)
+		return
+	}
+	switch d.options.DuplicateKeyPolicy {
+	case DuplicateKeyFirstWins:
+	case DuplicateKeyMerge:
+		m.Set(key, mergeDuplicateValue(existing, value))
+	default:
+		m.Set(key, value)
+	}
+}
+
+// mergeDuplicateValue 合并重复键的两个值：mapping递归合并，sequence拼接，其余情况返回后出现的值
+func mergeDuplicateValue(existing, value interface{}) interface{} {
+	switch e := existing.(type) {
+	case map[string]interface{}:
+		if v, ok := value.(map[string]interface{}); ok {
+			for k, item := range v {
+				if old, exists := e[k]; exists {
+					item = mergeDuplicateValue(old, item)
+				}
+				e[k] = item
+			}
+			return e
+		}
+	case *OrderedMap:
+		if v, ok := value.(*OrderedMap); ok {
+			v.Range(func(k string, item interface{}) bool {
+				if old, exists := e.Get(k); exists {
+					item = mergeDuplicateValue(old, item)
+				}
+				e.Set(k, item)
+				return true
+			})
+			return e
+		}
+	case []interface{}:
+		if v, ok := value.([]interface{}); ok {
+			return append(e, v...)
+		}
+	}
+	return value
+}
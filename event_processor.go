@@ -4,32 +4,76 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
-	"regexp"
-	"strings"
 )
 
 // EventProcessor 事件处理器
 type EventProcessor struct {
 	logger Logger
+	opts   []ParserOption
 }
 
 // NewEventProcessor 创建新的事件处理器
-func NewEventProcessor(logger Logger) *EventProcessor {
+func NewEventProcessor(logger Logger, opts ...ParserOption) *EventProcessor {
 	return &EventProcessor{
 		logger: logger,
+		opts:   opts,
 	}
 }
 
-// ProcessAIResponseEvents 处理AI响应事件流
+// ProcessAIResponseEvents 处理AI响应事件流，根节点为sequence或标量时返回ErrRootNotMapping
 func (ep *EventProcessor) ProcessAIResponseEvents(ctx context.Context, eventChan chan SSEvent) (map[string]interface{}, error) {
+	result, err := ep.ParseAIResponseEvents(ctx, eventChan)
+	if err != nil {
+		return nil, err
+	}
+	return result.rootMap()
+}
+
+// ProcessAIResponseEventsOrdered 处理AI响应事件流，返回保留键顺序的OrderedMap
+func (ep *EventProcessor) ProcessAIResponseEventsOrdered(ctx context.Context, eventChan chan SSEvent) (*OrderedMap, error) {
+	ordered := NewEventProcessor(ep.logger, append(append([]ParserOption(nil), ep.opts...), WithOrderedMaps())...)
+	result, err := ordered.ParseAIResponseEvents(ctx, eventChan)
+	if err != nil {
+		return nil, err
+	}
+	return result.rootOrderedMap()
+}
+
+// ProcessAIResponseDocuments 处理AI响应事件流，按文档标记（---）返回多个文档
+func (ep *EventProcessor) ProcessAIResponseDocuments(ctx context.Context, eventChan chan SSEvent) ([]map[string]interface{}, error) {
+	result, err := ep.ParseAIResponseEvents(ctx, eventChan)
+	if err != nil {
+		return nil, err
+	}
+	return result.documentMaps()
+}
+
+// ParseAIResponseEvents 处理AI响应事件流，根节点可以是mapping、sequence或标量
+func (ep *EventProcessor) ParseAIResponseEvents(ctx context.Context, eventChan chan SSEvent) (*ParseResult, error) {
 	logEntry := ep.logger.WithContext(ctx).WithField("module", "yaml")
-	result := []string{}
-	allContent := ""
-	line := ""
+	assembler, err := ep.collectLines(ctx, eventChan, logEntry)
+	if err != nil {
+		return nil, err
+	}
 
-	// 正则表达式用于匹配YAML格式
-	re := regexp.MustCompile(`^.*[a-zA-Z]+:.*`)
-	re1 := regexp.MustCompile(`^.*[a-zA-Z]+: .+`)
+	// 将YAML行转换为解析结果，代码块标记已在拼装时去除，由拼装器判断输出是否在代码块中结束
+	yamlParser := NewYAMLParser(ep.logger, ep.opts...)
+	lines := assembler.Lines()
+	result, err := yamlParser.parse(ctx, lines, assembler.Open())
+	if err != nil {
+		logEntry.WithError(err).Error("yaml parse error")
+		return nil, fmt.Errorf("yaml parse error: %w", err)
+	}
+	result.Normalizations = mergeNormalizeReports(assembler.Normalizations(), result.Normalizations)
+
+	ep.logResult(logEntry, "yamlValue", result.Value)
+	return result, nil
+}
+
+// collectLines 读取事件流中的内容，返回已写入全部内容的行拼装器
+func (ep *EventProcessor) collectLines(ctx context.Context, eventChan chan SSEvent, logEntry Logger) (*lineAssembler, error) {
+	assembler := newLineAssembler(logEntry, newParserOptions(ep.opts...))
+	allContent := ""
 
 	for event := range eventChan {
 		// 如果上下文被取消，则退出
@@ -50,75 +94,31 @@ func (ep *EventProcessor) ProcessAIResponseEvents(ctx context.Context, eventChan
 		}
 
 		if choices, ok := rawData["Choices"].([]interface{}); ok && len(choices) > 0 {
-			if delta, ok := choices[0].(map[string]interface{})["Delta"].(map[string]interface{}); ok {
+			choice, _ := choices[0].(map[string]interface{})
+			if reason, ok := choice["FinishReason"].(string); ok && reason != "" {
+				assembler.finishReason = reason
+			}
+			if delta, ok := choice["Delta"].(map[string]interface{}); ok {
 				if contentValue, exists := delta["Content"]; exists {
 					content := contentValue.(string)
-					line += content
+					// 按行拼装内容，处理行合并和块标量
+					assembler.Write(content)
 					allContent += content
-
-					if strings.HasSuffix(line, "\n") || strings.HasSuffix(line, "\\n") {
-						logEntry.Infof("line: %s\n", line)
-						preLine := ""
-						if len(result) > 0 {
-							preLine = result[len(result)-1]
-							logEntry.Infof("preLine: %s", preLine)
-						}
-
-						// 处理行合并逻辑
-						if !re.MatchString(line) && len(result) > 0 && (!strings.HasPrefix(strings.TrimSpace(line), "- ") || re1.MatchString(preLine)) {
-							line = preLine + line
-							result[len(result)-1] = line
-							line = ""
-							continue
-						}
-
-						tabCount := strings.Count(line, "\t")
-						logEntry.Infof("tabCount: %d", tabCount)
-
-						// 清理YAML标记
-						line = strings.TrimPrefix(line, "```yaml")
-						line = strings.TrimSuffix(line, "```")
-
-						if strings.TrimSpace(line) == "" {
-							continue
-						}
-
-						result = append(result, line)
-						line = ""
-					}
 				}
 			}
 		}
 	}
 
-	// 处理最后一行
-	tabCount := strings.Count(line, "\t")
-	logEntry.Infof("tabCount: %d", tabCount)
-	logEntry.Infof("最后一行line: %s", line)
 	logEntry.Infof("allContent: %s", allContent)
+	return assembler, nil
+}
 
-	line = strings.TrimPrefix(line, "```yaml\n")
-	line = strings.TrimSuffix(line, "\n```")
-	line = strings.TrimSuffix(line, "```")
-
-	if strings.TrimSpace(line) != "" {
-		result = append(result, line)
-	}
-
-	// 将YAML行转换为map
-	yamlParser := NewYAMLParser(ep.logger)
-	yamlMap, err := yamlParser.LinesToMap(ctx, result)
-	if err != nil {
-		logEntry.WithError(err).Error("yamlLinesToMap error")
-		return nil, fmt.Errorf("yamlLinesToMap error: %v", err)
-	}
-
-	jsonData, err := json.Marshal(yamlMap)
+// logResult 以JSON格式记录解析结果，无法序列化（如包含NaN）时按默认格式记录
+func (ep *EventProcessor) logResult(logEntry Logger, name string, result interface{}) {
+	jsonData, err := json.Marshal(result)
 	if err != nil {
-		logEntry.WithError(err).Error("json marshal error")
-		return nil, fmt.Errorf("json marshal error: %v", err)
+		logEntry.Infof("%s: %v", name, result)
+		return
 	}
-
-	logEntry.Infof("yamlMap: %v", string(jsonData))
-	return yamlMap, nil
+	logEntry.Infof("%s: %v", name, string(jsonData))
 }
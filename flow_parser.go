@@ -0,0 +1,267 @@
+package aiyaml
+
+import (
+	"fmt"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// flowParser 流式集合（[a, b]、{k: v}）解析器
+type flowParser struct {
+	lp     *lineParser
+	text   string
+	pos    int
+	line   int
+	column int
+}
+
+// parseFlowCollection 解析完整的流式集合文本，line和column为文本起始位置
+func (lp *lineParser) parseFlowCollection(text string, line, column int) (*yaml.Node, error) {
+	fp := &flowParser{lp: lp, text: text, line: line, column: column}
+	node, err := fp.parseNode()
+	if err != nil {
+		return nil, err
+	}
+	fp.skipSpaces()
+	if fp.pos < len(fp.text) && fp.text[fp.pos] != '#' {
+		return nil, fp.errorf("流式集合之后存在多余内容: %s", fp.text[fp.pos:])
+	}
+	return node, nil
+}
+
+// flowDepth 计算文本中未闭合的流式括号层数，忽略引号内的内容
+func flowDepth(text string) int {
+	depth := 0
+	forEachUnquoted(text, func(i int) bool {
+		switch text[i] {
+		case '[', '{':
+			depth++
+		case ']', '}':
+			depth--
+		}
+		return true
+	})
+	return depth
+}
+
+// isFlowStart 判断值是否以流式集合开头
+func isFlowStart(value string) bool {
+	return strings.HasPrefix(value, "[") || strings.HasPrefix(value, "{")
+}
+
+// parseNode 解析一个流式节点
+func (fp *flowParser) parseNode() (*yaml.Node, error) {
+	fp.skipSpaces()
+	if fp.pos >= len(fp.text) {
+		return nil, fp.errorf("流式集合未闭合")
+	}
+	switch fp.text[fp.pos] {
+	case '&':
+		anchor := fp.parseName()
+		node, err := fp.parseNode()
+		if err != nil {
+			return nil, err
+		}
+		fp.lp.setAnchor(node, anchor)
+		return node, nil
+	case '*':
+		column := fp.column + fp.pos
+		return fp.lp.newAlias(fp.parseName(), fp.line, column), nil
+	case '!':
+		tag, rest := splitTag(fp.text[fp.pos:])
+		if tag == "" {
+			break
+		}
+		fp.pos = len(fp.text) - len(rest)
+		node, err := fp.parseNode()
+		if err != nil {
+			return nil, err
+		}
+		node.Tag, node.Style = tag, node.Style|yaml.TaggedStyle
+		return node, nil
+	case '[':
+		return fp.parseSequence()
+	case '{':
+		return fp.parseMapping()
+	case '"', '\'':
+		return fp.parseQuoted()
+	}
+	return fp.parsePlain(), nil
+}
+
+// parseSequence 解析 [a, b, c]
+func (fp *flowParser) parseSequence() (*yaml.Node, error) {
+	node := fp.newNode(yaml.SequenceNode, "!!seq")
+	fp.pos++
+	for {
+		fp.skipSpaces()
+		if fp.pos >= len(fp.text) {
+			return nil, fp.errorf("流式数组未闭合")
+		}
+		if fp.text[fp.pos] == ']' {
+			fp.pos++
+			return node, nil
+		}
+		item, err := fp.parseNode()
+		if err != nil {
+			return nil, err
+		}
+		fp.skipSpaces()
+		if fp.isValueIndicator() {
+			// [a: 1] 表示只有一个键值对的map
+			pair := &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map", Style: yaml.FlowStyle, Line: item.Line, Column: item.Column}
+			value, err := fp.parseMappingValue()
+			if err != nil {
+				return nil, err
+			}
+			pair.Content = append(pair.Content, item, value)
+			item = pair
+		}
+		node.Content = append(node.Content, item)
+		if err := fp.parseSeparator(']'); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// parseMapping 解析 {k: v, k2: v2}
+func (fp *flowParser) parseMapping() (*yaml.Node, error) {
+	node := fp.newNode(yaml.MappingNode, "!!map")
+	fp.pos++
+	for {
+		fp.skipSpaces()
+		if fp.pos >= len(fp.text) {
+			return nil, fp.errorf("流式map未闭合")
+		}
+		if fp.text[fp.pos] == '}' {
+			fp.pos++
+			return node, nil
+		}
+		key, err := fp.parseNode()
+		if err != nil {
+			return nil, err
+		}
+		fp.skipSpaces()
+		var value *yaml.Node
+		if fp.isValueIndicator() {
+			if value, err = fp.parseMappingValue(); err != nil {
+				return nil, err
+			}
+		} else {
+			// 只有键没有值时值为null
+			value = fp.newNode(yaml.ScalarNode, "!!null")
+		}
+		node.Content = append(node.Content, key, value)
+		if err := fp.parseSeparator('}'); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// parseName 解析锚点或别名的名称
+func (fp *flowParser) parseName() string {
+	fp.pos++
+	start := fp.pos
+	for fp.pos < len(fp.text) && !strings.ContainsRune(" \t,[]{}", rune(fp.text[fp.pos])) {
+		fp.pos++
+	}
+	return fp.text[start:fp.pos]
+}
+
+// parseMappingValue 跳过冒号并解析值，值缺省时为null
+func (fp *flowParser) parseMappingValue() (*yaml.Node, error) {
+	fp.pos++
+	fp.skipSpaces()
+	if fp.pos < len(fp.text) && (fp.text[fp.pos] == ',' || fp.text[fp.pos] == '}' || fp.text[fp.pos] == ']') {
+		return fp.newNode(yaml.ScalarNode, "!!null"), nil
+	}
+	return fp.parseNode()
+}
+
+// parseSeparator 解析条目之间的逗号，允许末尾多余的逗号
+func (fp *flowParser) parseSeparator(closing byte) error {
+	fp.skipSpaces()
+	if fp.pos >= len(fp.text) {
+		return fp.errorf("流式集合未闭合")
+	}
+	switch fp.text[fp.pos] {
+	case ',':
+		fp.pos++
+		return nil
+	case closing:
+		return nil
+	}
+	return fp.errorf("流式集合中缺少逗号: %s", fp.text[fp.pos:])
+}
+
+// parseQuoted 解析引号包裹的标量
+func (fp *flowParser) parseQuoted() (*yaml.Node, error) {
+	node := fp.newNode(yaml.ScalarNode, "!!str")
+	end := quotedEnd(fp.text, fp.pos)
+	if end < 0 {
+		return nil, fp.errorf("引号未闭合")
+	}
+	raw := fp.text[fp.pos : end+1]
+	fp.pos = end + 1
+	node.Style = yaml.DoubleQuotedStyle
+	if raw[0] == '\'' {
+		node.Style = yaml.SingleQuotedStyle
+	}
+	node.Value = unquoteScalar(raw)
+	return node, nil
+}
+
+// parsePlain 解析普通标量，遇到流式指示符或": "时结束
+func (fp *flowParser) parsePlain() *yaml.Node {
+	node := fp.newNode(yaml.ScalarNode, "")
+	start := fp.pos
+	for fp.pos < len(fp.text) {
+		ch := fp.text[fp.pos]
+		if ch == ',' || ch == '[' || ch == ']' || ch == '{' || ch == '}' || fp.isValueIndicator() {
+			break
+		}
+		if ch == '#' && fp.pos > start && (fp.text[fp.pos-1] == ' ' || fp.text[fp.pos-1] == '\t') {
+			break
+		}
+		fp.pos++
+	}
+	node.Value = strings.TrimSpace(fp.text[start:fp.pos])
+	return node
+}
+
+// isValueIndicator 判断当前位置是否为键值分隔的冒号
+func (fp *flowParser) isValueIndicator() bool {
+	if fp.pos >= len(fp.text) || fp.text[fp.pos] != ':' {
+		return false
+	}
+	if fp.pos+1 == len(fp.text) {
+		return true
+	}
+	switch fp.text[fp.pos+1] {
+	case ' ', '\t', ',', '[', ']', '{', '}':
+		return true
+	}
+	return false
+}
+
+// skipSpaces 跳过空白
+func (fp *flowParser) skipSpaces() {
+	for fp.pos < len(fp.text) && (fp.text[fp.pos] == ' ' || fp.text[fp.pos] == '\t') {
+		fp.pos++
+	}
+}
+
+// newNode 创建位于当前位置的节点
+func (fp *flowParser) newNode(kind yaml.Kind, tag string) *yaml.Node {
+	node := &yaml.Node{Kind: kind, Tag: tag, Line: fp.line, Column: fp.column + fp.pos}
+	if kind != yaml.ScalarNode {
+		node.Style = yaml.FlowStyle
+	}
+	return node
+}
+
+// errorf 生成带位置信息的错误，片段为整个流式集合文本
+func (fp *flowParser) errorf(format string, args ...interface{}) error {
+	return &ParseError{Line: fp.line, Column: fp.column + fp.pos, Snippet: fp.text, Reason: fmt.Sprintf(format, args...)}
+}
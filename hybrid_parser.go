@@ -0,0 +1,57 @@
+package aiyaml
+
+import (
+	"errors"
+	"io"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ParsePath 解析结果使用的解析方式
+type ParsePath int
+
+const (
+	// ParsePathTolerant 容错解析器
+	ParsePathTolerant ParsePath = iota
+	// ParsePathStrict yaml.v3（混合解析时输入格式正确）
+	ParsePathStrict
+)
+
+// String 返回解析方式的名称
+func (p ParsePath) String() string {
+	if p == ParsePathStrict {
+		return "strict"
+	}
+	return "tolerant"
+}
+
+// parseStrict 用yaml.v3解析完整文档，返回每个非空文档的根节点。
+// 代码块标记行替换为空行，保证节点的行号与输入一致
+func parseStrict(lines []string) ([]*yaml.Node, error) {
+	rows := strings.Split(strings.Join(lines, "\n"), "\n")
+	for i, row := range rows {
+		if strings.HasPrefix(strings.TrimSpace(row), "```") {
+			rows[i] = ""
+		}
+	}
+	decoder := yaml.NewDecoder(strings.NewReader(strings.Join(rows, "\n")))
+	var roots []*yaml.Node
+	for {
+		var doc yaml.Node
+		err := decoder.Decode(&doc)
+		if errors.Is(err, io.EOF) {
+			return roots, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		if len(doc.Content) > 0 {
+			// 文档的头注释和尾注释归属到根节点
+			root := doc.Content[0]
+			root.HeadComment = joinComments(doc.HeadComment, root.HeadComment)
+			root.FootComment = joinComments(root.FootComment, doc.FootComment)
+			roots = append(roots, root)
+		}
+	}
+}
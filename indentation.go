@@ -0,0 +1,123 @@
+package aiyaml
+
+import (
+	"fmt"
+	"strings"
+)
+
+// 缩进规则的默认值
+const (
+	defaultTabWidth   = 2 // 制表符宽度（列）
+	defaultIndentUnit = 2 // 每一级缩进的列数
+)
+
+// indentColumns 计算行首空白占用的列数，制表符前进到下一个tabWidth整数倍的列
+func indentColumns(line string, tabWidth int) int {
+	columns := 0
+	for _, ch := range line {
+		switch ch {
+		case ' ':
+			columns++
+		case '\t':
+			columns += tabWidth - columns%tabWidth
+		default:
+			return columns
+		}
+	}
+	return columns
+}
+
+// trimIndent 去除行首指定列数的空白，制表符跨越边界时多出的列以空格补齐
+func trimIndent(line string, columns, tabWidth int) string {
+	current := 0
+	for i, ch := range line {
+		if current >= columns {
+			return strings.Repeat(" ", current-columns) + line[i:]
+		}
+		switch ch {
+		case ' ':
+			current++
+		case '\t':
+			current += tabWidth - current%tabWidth
+		default:
+			return line[i:]
+		}
+	}
+	return ""
+}
+
+// contentColumn 返回行中跳过数组项标记（"- "）之后内容所在的列
+func contentColumn(line string, tabWidth int) int {
+	column := indentColumns(line, tabWidth)
+	text := strings.TrimLeft(line, " \t")
+	for isSeqItem(text) {
+		rest := strings.TrimLeft(text[1:], " \t")
+		column += indentColumns(text[1:len(text)-len(rest)], tabWidth) + 1
+		text = rest
+	}
+	return column
+}
+
+// detectIndentUnit 检测文档的缩进单位：取相邻行缩进增量中出现最多的值（相同时取较小值），
+// 紧凑写法中数组项内的兄弟键（"- a: 1" 之后的 "  b: 2"）不参与统计，无法检测时返回默认值
+func detectIndentUnit(lines []string, tabWidth int) int {
+	counts := make(map[int]int)
+	prevIndent, prevContent := 0, 0
+	for _, line := range lines {
+		text := strings.TrimSpace(line)
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+		indent := indentColumns(line, tabWidth)
+		if indent > prevIndent && indent != prevContent {
+			counts[indent-prevIndent]++
+		}
+		prevIndent, prevContent = indent, contentColumn(line, tabWidth)
+	}
+	unit, best := 0, 0
+	for delta, n := range counts {
+		if n > best || (n == best && delta < unit) {
+			unit, best = delta, n
+		}
+	}
+	if unit == 0 {
+		return defaultIndentUnit
+	}
+	return unit
+}
+
+// checkIndentation 检查缩进中制表符和空格的混用：同一行内混用，或与文档中先前的缩进方式不一致
+func checkIndentation(lines []string) []*ParseError {
+	var diagnostics []*ParseError
+	styleLine, styleTabs := 0, false
+	for i, line := range lines {
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		ws := line[:len(line)-len(strings.TrimLeft(line, " \t"))]
+		hasTabs, hasSpaces := strings.Contains(ws, "\t"), strings.Contains(ws, " ")
+		switch {
+		case hasTabs && hasSpaces:
+			diagnostics = append(diagnostics, &ParseError{Line: i + 1, Column: 1, Snippet: line, Reason: "缩进中混用了制表符和空格"})
+		case !hasTabs && !hasSpaces:
+		case styleLine == 0:
+			styleLine, styleTabs = i+1, hasTabs
+		case hasTabs != styleTabs:
+			diagnostics = append(diagnostics, &ParseError{
+				Line:    i + 1,
+				Column:  1,
+				Snippet: line,
+				Reason:  fmt.Sprintf("缩进使用%s，与第%d行的%s缩进不一致", indentStyleName(hasTabs), styleLine, indentStyleName(styleTabs)),
+			})
+		}
+	}
+	return diagnostics
+}
+
+// indentStyleName 返回缩进方式的名称
+func indentStyleName(tabs bool) string {
+	if tabs {
+		return "制表符"
+	}
+	return "空格"
+}
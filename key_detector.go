@@ -0,0 +1,126 @@
+package aiyaml
+
+import (
+	"regexp"
+	"strings"
+	"unicode/utf8"
+)
+
+// maxImplicitKeyLength 隐式键（没有 "?" 的键）的最大长度（字符），与YAML规范一致
+const maxImplicitKeyLength = 1024
+
+// colonTokenPattern 冒号属于值本身的记号：URL（https://example.com:8443/x）、时间（12:30、10:00:00.5）
+// 和Windows盘符路径（C:\temp、D:/data）。记号末尾的冒号不属于记号，仍可以是键的结束
+var colonTokenPattern = regexp.MustCompile(`^(?:[A-Za-z][A-Za-z0-9+.-]*://(?:\S*[^\s:])?|[0-9]{1,2}(?::[0-9]{2}){1,2}(?:\.[0-9]+)?|[A-Za-z]:[\\/](?:\S*[^\s:])?)`)
+
+// KeyDetector 键检测器，解析器、流式行拼装和YAMLRegexPatterns共用同一套规则：
+// 键与值之间的冒号必须后跟空白或位于行尾，且不在引号、流式括号、URL、时间或Windows路径内。
+// 因此URL（https://example.com、localhost:8080）、时间（12:30、10:00:00）
+// 和Windows路径（C:\temp、D:/data）中的冒号不会被当作键的结束，数组项中单独的盘符（- C:）也不是键
+type KeyDetector struct{}
+
+// NewKeyDetector 创建键检测器
+func NewKeyDetector() *KeyDetector {
+	return &KeyDetector{}
+}
+
+// IsKeyLine 判断行是否包含键，行首的缩进、数组项标记（-）和显式键值指示符（?、:）会被忽略
+func (kd *KeyDetector) IsKeyLine(line string) bool {
+	_, _, ok := kd.split(line)
+	return ok
+}
+
+// HasValue 判断行是否为键之后带有值的键值对
+func (kd *KeyDetector) HasValue(line string) bool {
+	_, value, ok := kd.split(line)
+	return ok && value != ""
+}
+
+// Split 拆分行中的键和值，去除行内注释，键和值去除引号
+func (kd *KeyDetector) Split(line string) (string, string, bool) {
+	key, value, ok := kd.split(line)
+	if !ok {
+		return "", "", false
+	}
+	return unquoteScalar(key), unquoteScalar(value), true
+}
+
+// split 去除行首空白、数组项标记、显式键值指示符和行内注释后拆分键值对
+func (kd *KeyDetector) split(line string) (string, string, bool) {
+	text := strings.TrimSpace(trimLineEnding(line))
+	item := false
+	for isSeqItem(text) || isExplicitKey(text) || isExplicitValue(text) {
+		item = item || text[0] == '-'
+		text = strings.TrimLeft(text[1:], " \t")
+	}
+	text = stripComment(text)
+	if item && isDriveLetter(text) {
+		return "", "", false
+	}
+	return splitKeyValue(text)
+}
+
+// LineMatcher 按行判断的规则，用法与*regexp.Regexp的MatchString一致
+type LineMatcher interface {
+	MatchString(line string) bool
+}
+
+// lineMatcherFunc 将判断函数适配为LineMatcher
+type lineMatcherFunc func(line string) bool
+
+// MatchString 判断行是否满足规则
+func (f lineMatcherFunc) MatchString(line string) bool {
+	return f(line)
+}
+
+// isKeyLine 判断内容是否为键值对
+func isKeyLine(text string) bool {
+	_, _, ok := splitKeyValue(text)
+	return ok
+}
+
+// isDriveLetter 判断内容是否为单独的Windows盘符（大写字母加冒号，如 "C:"）
+func isDriveLetter(text string) bool {
+	return len(text) == 2 && text[0] >= 'A' && text[0] <= 'Z' && text[1] == ':'
+}
+
+// colonTokenEnd 位置i是URL、时间或Windows路径的开头时返回记号的结束位置，否则返回i
+func colonTokenEnd(text string, i int) int {
+	if i > 0 && !strings.ContainsRune(" \t[{,", rune(text[i-1])) {
+		return i
+	}
+	return i + len(colonTokenPattern.FindString(text[i:]))
+}
+
+// splitKeyValue 在第一个后跟空白或位于行尾、且不在引号、流式括号、URL、时间或Windows路径内的冒号处拆分键值对，
+// 返回的键仍保留引号。超过maxImplicitKeyLength的键通常是包含冒号的长句，不视为键
+func splitKeyValue(text string) (string, string, bool) {
+	depth := 0
+	sep := -1
+	token := 0
+	forEachUnquoted(text, func(i int) bool {
+		if i >= token {
+			token = colonTokenEnd(text, i)
+		}
+		switch text[i] {
+		case '[', '{':
+			depth++
+		case ']', '}':
+			depth--
+		case ':':
+			if i >= token && depth == 0 && (i+1 == len(text) || text[i+1] == ' ' || text[i+1] == '\t') {
+				sep = i
+				return false
+			}
+		}
+		return true
+	})
+	if sep < 0 {
+		return "", "", false
+	}
+	key := strings.TrimSpace(text[:sep])
+	if key == "" || utf8.RuneCountInString(key) > maxImplicitKeyLength {
+		return "", "", false
+	}
+	return key, strings.TrimSpace(text[sep+1:]), true
+}
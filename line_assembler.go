@@ -0,0 +1,217 @@
+package aiyaml
+
+import (
+	"strings"
+)
+
+// lineAssembler 将流式返回的内容片段拼装为YAML行
+type lineAssembler struct {
+	logger        Logger
+	stringUtils   *StringUtils
+	regexPatterns *YAMLRegexPatterns
+	lines         []string
+	line          string
+	blockIndent   int               // 块标量所属行的缩进，-1表示不在块标量中
+	fences        int               // 已去除的代码块标记（```）数量，为奇数时输出在代码块中结束
+	afterFence    bool              // 上一个有效行是代码块标记，下一行不与代码块之前的行合并
+	finishReason  string            // 事件流中模型返回的结束原因
+	normalizer    *normalizeSession // 启用规范化时在行分类之前规范化每一行，未启用时为nil
+	normalized    *NormalizeReport  // 拼装过程中的规范化报告，行号为拼装后的行号
+}
+
+// newLineAssembler 按解析器配置创建行拼装器，缩进规则与解析器一致
+func newLineAssembler(logger Logger, options ParserOptions) *lineAssembler {
+	la := &lineAssembler{
+		logger:        logger,
+		stringUtils:   newStringUtilsWithOptions(options),
+		regexPatterns: NewYAMLRegexPatterns(),
+		blockIndent:   -1,
+	}
+	if options.Normalize {
+		la.normalizer = newNormalizer(logger, options).newSession()
+		la.normalized = &NormalizeReport{}
+	}
+	return la
+}
+
+// Write 追加内容片段，遇到换行符（或字面量"\n"）时提交完整行
+func (la *lineAssembler) Write(content string) {
+	la.line += content
+	for {
+		idx := strings.Index(la.line, "\n")
+		if idx < 0 {
+			break
+		}
+		la.commit(la.line[:idx+1])
+		la.line = la.line[idx+1:]
+	}
+	if strings.HasSuffix(la.line, "\\n") {
+		la.commit(la.line)
+		la.line = ""
+	}
+}
+
+// Lines 提交剩余内容并返回拼装好的行
+func (la *lineAssembler) Lines() []string {
+	line := la.line
+	if la.normalizer != nil {
+		var changes []NormalizeChange
+		line, changes = la.normalize(line)
+		defer la.recordNormalizations(changes)
+	}
+	la.line = ""
+	if isFenceLine(line) {
+		la.fences++
+		return la.lines
+	}
+	line = la.stringUtils.CleanYAMLMarkers(line)
+	if strings.TrimSpace(line) != "" {
+		la.lines = append(la.lines, trimLineEnding(line))
+	}
+	return la.lines
+}
+
+// Open 判断输出是否在中途结束：在未闭合的代码块中结束，或事件流的结束原因为达到长度上限。需要在Lines之后调用
+func (la *lineAssembler) Open() bool {
+	return isOpenOutput(la.lines, la.fences) || isLengthFinish(la.finishReason)
+}
+
+// Normalizations 返回拼装过程中的规范化报告，未启用规范化时为nil
+func (la *lineAssembler) Normalizations() *NormalizeReport {
+	return la.normalized
+}
+
+// commit 处理一个完整行：先按需规范化，块标量内容原样保留，其余行按需与上一行合并
+func (la *lineAssembler) commit(line string) {
+	la.logger.Infof("line: %s", line)
+	if la.normalizer != nil {
+		var changes []NormalizeChange
+		line, changes = la.normalize(line)
+		defer la.recordNormalizations(changes)
+	}
+
+	if la.blockIndent >= 0 {
+		if strings.TrimSpace(trimLineEnding(line)) == "" {
+			la.lines = append(la.lines, "")
+			return
+		}
+		if la.stringUtils.IndentColumns(line) > la.blockIndent {
+			la.lines = append(la.lines, trimLineEnding(line))
+			return
+		}
+		la.blockIndent = -1
+	}
+
+	if isFenceLine(line) {
+		// 代码块标记不属于YAML内容，不与上一行合并，只记录数量
+		la.fences++
+		la.afterFence = true
+		return
+	}
+	if text := trimLineEnding(line); isDocumentMarker(text) {
+		// 文档标记和指令独占一行
+		la.lines = append(la.lines, strings.TrimRight(text, " \t"))
+		return
+	}
+
+	preLine := ""
+	if len(la.lines) > 0 {
+		preLine = la.lines[len(la.lines)-1]
+	}
+	text := strings.TrimSpace(trimLineEnding(line))
+	if hasOpenFlowValue(preLine) {
+		// 流式集合尚未闭合，续行拼接到上一行，集合内的整行注释丢弃
+		if text != "" && !strings.HasPrefix(text, "#") {
+			la.lines[len(la.lines)-1] = preLine + " " + text
+		}
+		return
+	}
+	if strings.HasPrefix(text, "#") {
+		// 整行注释独占一行，与上一行合并会把注释拼接到值中
+		la.lines = append(la.lines, strings.TrimRight(trimLineEnding(line), " \t"))
+		return
+	}
+	if !la.regexPatterns.KeyDetector.IsKeyLine(line) && len(la.lines) > 0 && !isDocumentMarker(preLine) && !la.afterFence &&
+		!isExplicitKey(text) && !isExplicitValue(text) && (!isSeqItem(text) || la.continuesValue(line, preLine)) {
+		la.lines[len(la.lines)-1] = trimLineEnding(preLine + line)
+		return
+	}
+
+	line = la.stringUtils.CleanYAMLMarkers(line)
+	if strings.TrimSpace(line) == "" {
+		return
+	}
+	line = trimLineEnding(line)
+	la.lines = append(la.lines, line)
+	la.afterFence = false
+	if startsBlockScalar(line) {
+		la.blockIndent = la.stringUtils.IndentColumns(line)
+	}
+}
+
+// normalize 规范化一行，保留行尾的换行符（或字面量"\n"）
+func (la *lineAssembler) normalize(line string) (string, []NormalizeChange) {
+	body := strings.TrimSuffix(line, "\n")
+	if body == line {
+		body = strings.TrimSuffix(line, "\\n")
+	}
+	normalized, changes := la.normalizer.normalize(body)
+	return normalized + line[len(body):], changes
+}
+
+// recordNormalizations 记录规范化修改，行号为修改所在的拼装后的行（合并到上一行时为上一行）
+func (la *lineAssembler) recordNormalizations(changes []NormalizeChange) {
+	for _, c := range changes {
+		c.Line = len(la.lines)
+		if c.Line == 0 {
+			c.Line = 1
+		}
+		la.normalized.Changes = append(la.normalized.Changes, c)
+	}
+}
+
+// continuesValue 判断数组项行是否为上一行值的续行：只有上一行为带值的键且该行缩进比键更深时才视为续行，
+// 否则（如 "- a: 1" 之后的 "- b"、嵌套数组 "- - a"）作为独立的数组项保留
+func (la *lineAssembler) continuesValue(line, preLine string) bool {
+	if !la.regexPatterns.KeyDetector.HasValue(preLine) {
+		return false
+	}
+	return la.stringUtils.IndentColumns(line) > contentColumn(preLine, la.stringUtils.tabWidth())
+}
+
+// isFenceLine 判断行是否为代码块标记（```、```yaml）
+func isFenceLine(line string) bool {
+	return strings.HasPrefix(strings.TrimSpace(trimLineEnding(line)), "```")
+}
+
+// trimLineEnding 去除行尾的换行符和字面量"\n"
+func trimLineEnding(line string) string {
+	line = strings.TrimRight(line, "\r\n")
+	line = strings.TrimSuffix(line, "\\n")
+	return strings.TrimRight(line, "\r\n")
+}
+
+// startsBlockScalar 判断行的值是否为块标量头部
+func startsBlockScalar(line string) bool {
+	_, ok := parseBlockScalarHeader(lineValue(line))
+	return ok
+}
+
+// hasOpenFlowValue 判断行的值是否为尚未闭合的流式集合
+func hasOpenFlowValue(line string) bool {
+	value := lineValue(line)
+	return isFlowStart(value) && flowDepth(value) > 0
+}
+
+// lineValue 去除数组项标记、显式键值指示符（?、:）、键、锚点和标签，返回行中的值部分
+func lineValue(line string) string {
+	text := strings.TrimSpace(line)
+	for isSeqItem(text) || isExplicitKey(text) || isExplicitValue(text) {
+		text = strings.TrimLeft(text[1:], " \t")
+	}
+	if _, rest, ok := splitKeyValue(text); ok {
+		text = rest
+	}
+	_, _, text = splitProperties(text)
+	return text
+}
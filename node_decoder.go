@@ -0,0 +1,223 @@
+package aiyaml
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ErrAliasLimitExceeded 别名展开超出限制
+var ErrAliasLimitExceeded = errors.New("别名展开超出限制")
+
+// nodeDecoder 将解析得到的节点树转换为Go值，负责别名展开和合并键
+type nodeDecoder struct {
+	options    *ParserOptions
+	aliases    int // 已展开的别名次数
+	aliasNodes int // 通过别名展开产生的节点数
+	aliasDepth int // 当前所处的别名展开层数
+	err        error
+	warnings   []*ParseError // 标签解析失败等不影响结果的问题
+}
+
+// newNodeDecoder 创建节点解码器
+func newNodeDecoder(options *ParserOptions) *nodeDecoder {
+	return &nodeDecoder{options: options}
+}
+
+// decode 转换节点树，别名展开超出限制时返回错误
+func (d *nodeDecoder) decode(n *yaml.Node) (interface{}, error) {
+	value := d.decodeNode(n)
+	if d.err != nil {
+		return nil, d.err
+	}
+	return value, nil
+}
+
+// decodeNode 递归转换节点
+func (d *nodeDecoder) decodeNode(n *yaml.Node) interface{} {
+	if n == nil || d.err != nil {
+		return nil
+	}
+	if d.aliasDepth > 0 {
+		d.aliasNodes++
+		if d.aliasNodes > d.options.MaxAliasNodes {
+			d.err = fmt.Errorf("%w: 展开节点数超过 %d", ErrAliasLimitExceeded, d.options.MaxAliasNodes)
+			return nil
+		}
+	}
+	switch n.Kind {
+	case yaml.AliasNode:
+		return d.decodeAlias(n)
+	case yaml.MappingNode:
+		return d.tagged(n, d.decodeMapping(n))
+	case yaml.SequenceNode:
+		arr := make([]interface{}, 0, len(n.Content))
+		for _, item := range n.Content {
+			arr = append(arr, d.decodeNode(item))
+		}
+		return d.tagged(n, arr)
+	case yaml.ScalarNode:
+		if isTagged(n) {
+			return d.taggedScalar(n)
+		}
+		return d.untaggedScalar(n)
+	}
+	return nil
+}
+
+// tagged 集合带有显式标签时交给已注册的标签解析函数处理
+func (d *nodeDecoder) tagged(n *yaml.Node, value interface{}) interface{} {
+	if !isTagged(n) {
+		return value
+	}
+	return d.taggedCollection(n, value)
+}
+
+// decodeAlias 展开别名
+func (d *nodeDecoder) decodeAlias(n *yaml.Node) interface{} {
+	if n.Alias == nil {
+		return nil
+	}
+	d.aliases++
+	if d.aliases > d.options.MaxAliases {
+		d.err = fmt.Errorf("%w: 别名展开次数超过 %d", ErrAliasLimitExceeded, d.options.MaxAliases)
+		return nil
+	}
+	d.aliasDepth++
+	defer func() { d.aliasDepth-- }()
+	return d.decodeNode(n.Alias)
+}
+
+// decodeMapping 转换mapping，键按出现顺序排列，合并键（<<）的内容插入到合并键所在位置，
+// 显式键始终优先于合并得到的键。启用OrderedMaps时返回*OrderedMap，否则返回普通map
+func (d *nodeDecoder) decodeMapping(n *yaml.Node) interface{} {
+	explicit := make(map[string]bool, len(n.Content)/2)
+	for i := 0; i+1 < len(n.Content); i += 2 {
+		if !isMergeKey(n.Content[i]) || mergeSources(n.Content[i+1]) == nil {
+			explicit[mappingKey(n.Content[i])] = true
+		}
+	}
+	m := NewOrderedMap()
+	for i := 0; i+1 < len(n.Content); i += 2 {
+		key, value := n.Content[i], n.Content[i+1]
+		if isMergeKey(key) && mergeSources(value) != nil {
+			d.mergeInto(m, value, explicit)
+			continue
+		}
+		d.setMappingValue(m, mappingKey(key), d.decodeMappingValue(value))
+	}
+	if d.options.OrderedMaps {
+		return m
+	}
+	return m.values
+}
+
+// mergeInto 将合并键引用的mapping合并到m中，已存在的键和显式键不会被覆盖，
+// 合并列表中靠前的mapping优先
+func (d *nodeDecoder) mergeInto(m *OrderedMap, value *yaml.Node, explicit map[string]bool) {
+	add := func(k string, v interface{}) bool {
+		if _, exists := m.Get(k); !exists && !explicit[k] {
+			m.Set(k, v)
+		}
+		return true
+	}
+	for _, source := range mergeSources(value) {
+		switch merged := d.decodeNode(source).(type) {
+		case *OrderedMap:
+			merged.Range(add)
+		case map[string]interface{}:
+			for k, v := range merged {
+				add(k, v)
+			}
+		}
+	}
+}
+
+// untaggedScalar 根据解析器选项转换没有显式标签的标量值，引号包裹的标量始终保留为字符串。
+// 空值和普通标量 ~、null 无论是否启用TypedScalars都为nil
+func (d *nodeDecoder) untaggedScalar(n *yaml.Node) interface{} {
+	if n.Tag == "!!null" && n.Value == "" && !isTagged(n) {
+		return nil
+	}
+	if n.Style&^yaml.TaggedStyle != 0 || isQuotedScalar(n.Value) {
+		return n.Value
+	}
+	if !d.options.TypedScalars {
+		if isNullLiteral(n.Value) {
+			return nil
+		}
+		return n.Value
+	}
+	return resolveScalar(n.Value)
+}
+
+// decodeMappingValue 转换mapping中的值，启用TypedEmpties时没有内容的值（key:）转换为空mapping
+func (d *nodeDecoder) decodeMappingValue(n *yaml.Node) interface{} {
+	if d.options.TypedEmpties && isEmptyValue(n) {
+		return d.decodeMapping(&yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"})
+	}
+	return d.decodeNode(n)
+}
+
+// isEmptyValue 判断节点是否为没有任何内容的值（不包括 ~、null 和带标签的空值）
+func isEmptyValue(n *yaml.Node) bool {
+	return n != nil && n.Kind == yaml.ScalarNode && n.Tag == "!!null" && n.Value == "" && n.Style == 0
+}
+
+// mappingKey 将键节点转换为map的键：标量键使用原始文本（200 -> "200"，true -> "true"，~ -> "~"），
+// 空的显式键为""，sequence和mapping键按流式写法转换（[a, b]、{x: 1}），别名键使用其指向的节点
+func mappingKey(n *yaml.Node) string {
+	n = resolveAlias(n)
+	if n == nil {
+		return ""
+	}
+	switch n.Kind {
+	case yaml.SequenceNode:
+		items := make([]string, len(n.Content))
+		for i, item := range n.Content {
+			items[i] = mappingKey(item)
+		}
+		return "[" + strings.Join(items, ", ") + "]"
+	case yaml.MappingNode:
+		pairs := make([]string, 0, len(n.Content)/2)
+		for i := 0; i+1 < len(n.Content); i += 2 {
+			pairs = append(pairs, mappingKey(n.Content[i])+": "+mappingKey(n.Content[i+1]))
+		}
+		return "{" + strings.Join(pairs, ", ") + "}"
+	}
+	return n.Value
+}
+
+// isMergeKey 判断是否为合并键 <<
+func isMergeKey(n *yaml.Node) bool {
+	return n.Kind == yaml.ScalarNode && n.Style == 0 && n.Value == "<<"
+}
+
+// mergeSources 返回合并键的值所引用的mapping列表，值不是mapping（或mapping列表）时返回nil
+func mergeSources(value *yaml.Node) []*yaml.Node {
+	target := resolveAlias(value)
+	switch {
+	case target == nil:
+		return nil
+	case target.Kind == yaml.MappingNode:
+		return []*yaml.Node{value}
+	case target.Kind == yaml.SequenceNode:
+		for _, item := range target.Content {
+			if t := resolveAlias(item); t == nil || t.Kind != yaml.MappingNode {
+				return nil
+			}
+		}
+		return target.Content
+	}
+	return nil
+}
+
+// resolveAlias 返回别名指向的节点，非别名节点原样返回
+func resolveAlias(n *yaml.Node) *yaml.Node {
+	for n != nil && n.Kind == yaml.AliasNode {
+		n = n.Alias
+	}
+	return n
+}
@@ -0,0 +1,60 @@
+package aiyaml
+
+import (
+	"context"
+
+	"gopkg.in/yaml.v3"
+)
+
+// LinesToNodes 将yaml代码行解析为yaml.v3的节点树，每个文档对应一个DocumentNode。
+// 节点保留行列位置、标量样式、头部注释和行尾注释，标签按yaml.v3的规则补全，
+// 可以直接调用node.Decode或用yaml.v3重新编码
+func (yp *YAMLParser) LinesToNodes(ctx context.Context, lines []string) ([]*yaml.Node, error) {
+	parsed, err := yp.parseNodes(ctx, lines, isOpenOutput(lines, 0))
+	if err != nil {
+		return nil, err
+	}
+	nodes := make([]*yaml.Node, 0, len(parsed.roots))
+	for _, root := range parsed.roots {
+		nodes = append(nodes, documentNode(root))
+	}
+	return nodes, nil
+}
+
+// LinesToNode 将yaml代码行解析为节点树并返回第一个文档，没有内容时返回空的DocumentNode
+func (yp *YAMLParser) LinesToNode(ctx context.Context, lines []string) (*yaml.Node, error) {
+	nodes, err := yp.LinesToNodes(ctx, lines)
+	if err != nil {
+		return nil, err
+	}
+	if len(nodes) == 0 {
+		return &yaml.Node{Kind: yaml.DocumentNode, Line: 1, Column: 1}, nil
+	}
+	return nodes[0], nil
+}
+
+// documentNode 为根节点创建DocumentNode并补全标签
+func documentNode(root *yaml.Node) *yaml.Node {
+	doc := &yaml.Node{Kind: yaml.DocumentNode, Line: 1, Column: 1}
+	if root == nil {
+		return doc
+	}
+	fillTags(root)
+	doc.Line, doc.Column = root.Line, root.Column
+	doc.Content = []*yaml.Node{root}
+	return doc
+}
+
+// fillTags 为没有显式标签的节点补全yaml.v3解析时得到的标签（!!str、!!int、!!map等），
+// 别名节点不展开
+func fillTags(n *yaml.Node) {
+	if n.Kind == yaml.AliasNode {
+		return
+	}
+	if n.Tag == "" {
+		n.Tag = n.ShortTag()
+	}
+	for _, child := range n.Content {
+		fillTags(child)
+	}
+}
@@ -0,0 +1,378 @@
+package aiyaml
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// NormalizeRule 规范化规则的名称
+type NormalizeRule string
+
+// 规范化支持的规则，按下列顺序依次应用于每一行
+const (
+	// NormalizeLineEnding 去除\r\n行尾中的\r
+	NormalizeLineEnding NormalizeRule = "line-ending"
+	// NormalizeBOM 去除行首的字节顺序标记（U+FEFF）
+	NormalizeBOM NormalizeRule = "bom"
+	// NormalizeZeroWidth 删除零宽字符（U+200B、U+2060、U+FEFF），零宽连接符（U+200C、U+200D）只在emoji序列中保留
+	NormalizeZeroWidth NormalizeRule = "zero-width"
+	// NormalizeUnicodeSpace 将缩进、数组项标记之后和键的冒号之后的不换行空格（U+00A0等）替换为空格，全角空格（U+3000）替换为两个空格
+	NormalizeUnicodeSpace NormalizeRule = "unicode-space"
+	// NormalizeFullWidthColon 将键之后的全角冒号替换为": "（"名称：值" -> "名称: 值"），
+	// 数组项中的全角冒号只在键较短、值不像句子时替换（"- 注意：不要删除" 保持不变）
+	NormalizeFullWidthColon NormalizeRule = "full-width-colon"
+	// NormalizeSmartQuotes 将包裹键或整个值的弯引号（“”、‘’）替换为YAML引号
+	NormalizeSmartQuotes NormalizeRule = "smart-quotes"
+)
+
+// NormalizeRules 所有规范化规则，WithNormalization未指定规则时全部启用
+var NormalizeRules = []NormalizeRule{
+	NormalizeLineEnding,
+	NormalizeBOM,
+	NormalizeZeroWidth,
+	NormalizeUnicodeSpace,
+	NormalizeFullWidthColon,
+	NormalizeSmartQuotes,
+}
+
+// NormalizeChange 规范化对一行所做的一次修改
+type NormalizeChange struct {
+	Line   int           // 行号，从1开始
+	Rule   NormalizeRule // 应用的规则
+	Before string        // 修改前的行
+	After  string        // 修改后的行
+}
+
+// NormalizeReport 规范化报告，按行号顺序列出所有修改，同一行的多次修改按规则的应用顺序排列
+type NormalizeReport struct {
+	Changes []NormalizeChange
+}
+
+// Count 返回指定规则的修改次数
+func (r *NormalizeReport) Count(rule NormalizeRule) int {
+	n := 0
+	for _, c := range r.Changes {
+		if c.Rule == rule {
+			n++
+		}
+	}
+	return n
+}
+
+// Fired 返回至少生效过一次的规则，按规则的应用顺序排列
+func (r *NormalizeReport) Fired() []NormalizeRule {
+	var fired []NormalizeRule
+	for _, rule := range NormalizeRules {
+		if r.Count(rule) > 0 {
+			fired = append(fired, rule)
+		}
+	}
+	return fired
+}
+
+// maxItemKeyLength 数组项中以全角冒号结束的键的最大长度（字符），更长的通常是说明文字
+const maxItemKeyLength = 20
+
+// fullWidthKeyPattern 匹配以全角冒号结束的键
+var fullWidthKeyPattern = regexp.MustCompile(`^([ \t]*(?:-[ \t]+)*)(` + keyNamePattern + `|[0-9]+|"[^"]*"|'[^']*')[ \t]*：[ \t\x{00a0}\x{3000}]*(.*)$`)
+
+// Normalizer 在行分类（键的识别、缩进计算）之前规范化模型输出中的Unicode字符和空白。
+// 块标量的内容只处理行尾、BOM和缩进，不改变文本
+type Normalizer struct {
+	logger   Logger
+	rules    map[NormalizeRule]bool
+	tabWidth int
+}
+
+// NewNormalizer 创建规范化器，通过WithNormalization指定启用的规则，未指定时启用全部规则
+func NewNormalizer(logger Logger, opts ...ParserOption) *Normalizer {
+	return newNormalizer(logger, newParserOptions(opts...))
+}
+
+// newNormalizer 根据解析器配置创建规范化器
+func newNormalizer(logger Logger, options ParserOptions) *Normalizer {
+	rules := options.NormalizeRules
+	if len(rules) == 0 {
+		rules = NormalizeRules
+	}
+	nz := &Normalizer{logger: logger, rules: make(map[NormalizeRule]bool, len(rules)), tabWidth: options.TabWidth}
+	for _, rule := range rules {
+		nz.rules[rule] = true
+	}
+	return nz
+}
+
+// Normalize 规范化yaml代码行，返回规范化后的行（包含换行符的行被拆分为多行）和规范化报告
+func (nz *Normalizer) Normalize(lines []string) ([]string, *NormalizeReport) {
+	session := nz.newSession()
+	report := &NormalizeReport{}
+	var physical []string
+	for _, line := range lines {
+		for _, raw := range strings.Split(line, "\n") {
+			normalized, changes := session.normalize(raw)
+			for _, c := range changes {
+				c.Line = len(physical) + 1
+				report.Changes = append(report.Changes, c)
+			}
+			physical = append(physical, normalized)
+		}
+	}
+	return physical, report
+}
+
+// normalizeSession 逐行规范化时跟踪块标量的上下文，批量处理和流式拼装共用
+type normalizeSession struct {
+	nz          *Normalizer
+	blockIndent int // 块标量所属行的缩进，-1表示不在块标量中
+}
+
+// newSession 创建规范化会话
+func (nz *Normalizer) newSession() *normalizeSession {
+	return &normalizeSession{nz: nz, blockIndent: -1}
+}
+
+// normalize 规范化一行（不包含\n），返回修改后的行和修改记录（行号由调用方填写）
+func (s *normalizeSession) normalize(line string) (string, []NormalizeChange) {
+	rules := NormalizeRules
+	if s.blockIndent >= 0 {
+		if strings.TrimSpace(line) == "" || indentColumns(normalizeSpaces(line), s.nz.tabWidth) > s.blockIndent {
+			rules = []NormalizeRule{NormalizeLineEnding, NormalizeBOM, NormalizeUnicodeSpace}
+		} else {
+			s.blockIndent = -1
+		}
+	}
+	var changes []NormalizeChange
+	for _, rule := range rules {
+		if !s.nz.rules[rule] {
+			continue
+		}
+		fixed := applyNormalizeRule(rule, line, s.blockIndent >= 0)
+		if fixed == line {
+			continue
+		}
+		changes = append(changes, NormalizeChange{Rule: rule, Before: line, After: fixed})
+		s.nz.logger.Infof("yaml normalize %s: %q -> %q", rule, line, fixed)
+		line = fixed
+	}
+	if s.blockIndent < 0 && startsBlockScalar(line) {
+		s.blockIndent = indentColumns(line, s.nz.tabWidth)
+	}
+	return line, changes
+}
+
+// applyNormalizeRule 对一行应用单个规则，inBlock表示该行是块标量的内容
+func applyNormalizeRule(rule NormalizeRule, line string, inBlock bool) string {
+	switch rule {
+	case NormalizeLineEnding:
+		return strings.TrimRight(line, "\r")
+	case NormalizeBOM:
+		return strings.TrimLeft(line, "\ufeff")
+	case NormalizeZeroWidth:
+		return removeZeroWidth(line)
+	case NormalizeUnicodeSpace:
+		if inBlock {
+			indent := len(line) - len(strings.TrimLeftFunc(line, isIndentSpace))
+			return replaceUnicodeSpaces(line[:indent]) + line[indent:]
+		}
+		return normalizeSpaces(line)
+	case NormalizeFullWidthColon:
+		if m := fullWidthKeyPattern.FindStringSubmatch(line); m != nil {
+			if strings.Contains(m[1], "-") && !isItemEntry(m[2], m[3]) {
+				break
+			}
+			if m[3] == "" {
+				return m[1] + m[2] + ":"
+			}
+			return m[1] + m[2] + ": " + m[3]
+		}
+	case NormalizeSmartQuotes:
+		return normalizeSmartQuotes(line)
+	}
+	return line
+}
+
+// removeZeroWidth 删除零宽字符
+func removeZeroWidth(line string) string {
+	if !strings.ContainsAny(line, "\u200b\u200c\u200d\u2060\ufeff") {
+		return line
+	}
+	runes := []rune(line)
+	var b strings.Builder
+	for i, r := range runes {
+		switch r {
+		case '\u200b', '\u2060', '\ufeff':
+			continue
+		case '\u200c', '\u200d':
+			if i == 0 || i == len(runes)-1 || !isEmojiPart(runes[i-1]) || !isEmojiPart(runes[i+1]) {
+				continue
+			}
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// isEmojiPart 判断字符是否可以出现在零宽连接符两侧组成emoji序列：emoji、肤色修饰符和变体选择符（U+FE0F）
+func isEmojiPart(r rune) bool {
+	switch {
+	case r >= 0x1F000 && r <= 0x1FAFF: // 表情、符号、肤色修饰符
+		return true
+	case r >= 0x2300 && r <= 0x23FF, r >= 0x2600 && r <= 0x27BF, r >= 0x2B00 && r <= 0x2BFF: // 杂项符号（⚕、❤、⬛）
+		return true
+	}
+	return r == 0xFE0F
+}
+
+// isItemEntry 判断数组项中全角冒号两侧是否像键值对而不是一句说明文字：
+// 键不超过maxItemKeyLength个字符，值为空、以引号或流式括号开头，或者不含空白、中日韩文字和句读标点
+func isItemEntry(key, value string) bool {
+	if utf8.RuneCountInString(key) > maxItemKeyLength {
+		return false
+	}
+	value = stripComment(value)
+	if value == "" || strings.ContainsAny(value[:1], "\"'[{") {
+		return true
+	}
+	for _, r := range value {
+		if unicode.IsSpace(r) || unicode.In(r, unicode.Han, unicode.Hiragana, unicode.Katakana, unicode.Hangul) ||
+			strings.ContainsRune("，。！？；、", r) {
+			return false
+		}
+	}
+	return true
+}
+
+// isUnicodeSpace 判断是否为需要替换的非ASCII空格：不换行空格、各种宽度的空格和全角空格
+func isUnicodeSpace(r rune) bool {
+	return r == '\u00a0' || r == '\u202f' || r == '\u3000' || (r >= '\u2000' && r <= '\u200a')
+}
+
+// isIndentSpace 判断是否为可能出现在缩进中的空白
+func isIndentSpace(r rune) bool {
+	return r == ' ' || r == '\t' || isUnicodeSpace(r)
+}
+
+// replaceUnicodeSpaces 将非ASCII空格替换为空格，全角空格占两列
+func replaceUnicodeSpaces(s string) string {
+	return strings.Map(func(r rune) rune {
+		if isUnicodeSpace(r) && r != '\u3000' {
+			return ' '
+		}
+		return r
+	}, strings.ReplaceAll(s, "\u3000", "  "))
+}
+
+// normalizeSpaces 替换缩进、数组项标记之后和键的冒号之后的非ASCII空格
+func normalizeSpaces(line string) string {
+	end := 0
+	for end < len(line) {
+		r, size := utf8.DecodeRuneInString(line[end:])
+		if isIndentSpace(r) {
+			end += size
+			continue
+		}
+		if next, _ := utf8.DecodeRuneInString(line[end+size:]); r == '-' && isIndentSpace(next) {
+			end += size
+			continue
+		}
+		break
+	}
+	rest := line[end:]
+	depth, sep := 0, -1
+	forEachUnquoted(rest, func(i int) bool {
+		switch rest[i] {
+		case '[', '{':
+			depth++
+		case ']', '}':
+			depth--
+		case ':':
+			if depth != 0 || i == 0 {
+				break
+			}
+			if i+1 == len(rest) || rest[i+1] == ' ' || rest[i+1] == '\t' {
+				return false
+			}
+			if r, _ := utf8.DecodeRuneInString(rest[i+1:]); isUnicodeSpace(r) {
+				sep = i + 1
+				return false
+			}
+		}
+		return true
+	})
+	if sep > 0 {
+		value := strings.TrimLeftFunc(rest[sep:], isIndentSpace)
+		rest = rest[:sep] + replaceUnicodeSpaces(rest[sep:len(rest)-len(value)]) + value
+	}
+	return replaceUnicodeSpaces(line[:end]) + rest
+}
+
+// normalizeSmartQuotes 替换包裹键、数组项或整个值的弯引号
+func normalizeSmartQuotes(line string) string {
+	if !strings.ContainsAny(line, "“‘") {
+		return line
+	}
+	start := len(line) - len(strings.TrimLeft(line, " \t"))
+	for isSeqItem(line[start:]) {
+		start = len(line) - len(strings.TrimLeft(line[start+1:], " \t"))
+	}
+	line = line[:start] + requoteSmart(line[start:], true)
+	content := strings.TrimRight(line[start:], " \t")
+	if _, value, ok := splitKeyValue(content); ok && value != "" {
+		valueStart := start + len(content) - len(value)
+		line = line[:valueStart] + requoteSmart(line[valueStart:], false)
+	}
+	return line
+}
+
+// requoteSmart 将s开头由弯引号包裹的标量替换为YAML引号。标量之后只能是行尾、注释，
+// 或者在allowKey时为键的冒号，否则（如引号只包裹了值的一部分）保持原样
+func requoteSmart(s string, allowKey bool) string {
+	var closing string
+	switch {
+	case strings.HasPrefix(s, "“"):
+		closing = "”"
+	case strings.HasPrefix(s, "‘"):
+		closing = "’"
+	default:
+		return s
+	}
+	open := utf8.RuneLen([]rune(s)[0])
+	for end := open; ; {
+		i := strings.Index(s[end:], closing)
+		if i < 0 {
+			return s
+		}
+		end += i
+		after := s[end+len(closing):]
+		rest := strings.TrimLeft(after, " \t")
+		if rest == "" || (strings.HasPrefix(rest, "#") && rest != after) || (allowKey && isExplicitValue(rest)) {
+			return quoteScalar(s[open:end]) + after
+		}
+		end += len(closing)
+	}
+}
+
+// mergeNormalizeReports 合并流式拼装和解析两个阶段的规范化报告，按行号排列
+func mergeNormalizeReports(streamed, parsed *NormalizeReport) *NormalizeReport {
+	if streamed == nil {
+		return parsed
+	}
+	if parsed != nil {
+		streamed.Changes = append(streamed.Changes, parsed.Changes...)
+	}
+	sort.SliceStable(streamed.Changes, func(i, j int) bool {
+		return streamed.Changes[i].Line < streamed.Changes[j].Line
+	})
+	return streamed
+}
+
+// quoteScalar 为文本加上YAML引号：不含双引号和反斜杠时使用双引号，否则使用单引号
+func quoteScalar(text string) string {
+	if !strings.ContainsAny(text, `"\`) {
+		return `"` + text + `"`
+	}
+	return "'" + strings.ReplaceAll(text, "'", "''") + "'"
+}
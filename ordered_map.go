@@ -0,0 +1,134 @@
+package aiyaml
+
+import (
+	"bytes"
+	"encoding/json"
+
+	"gopkg.in/yaml.v3"
+)
+
+// OrderedMap 按插入顺序保存键的map，用于保留模型输出键的顺序。
+// 零值可以直接使用，非并发安全
+type OrderedMap struct {
+	keys   []string
+	values map[string]interface{}
+}
+
+// NewOrderedMap 创建空的OrderedMap
+func NewOrderedMap() *OrderedMap {
+	return &OrderedMap{values: make(map[string]interface{})}
+}
+
+// Get 返回键对应的值
+func (om *OrderedMap) Get(key string) (interface{}, bool) {
+	value, ok := om.values[key]
+	return value, ok
+}
+
+// Set 设置键的值，新键追加到末尾，已存在的键保持原有位置
+func (om *OrderedMap) Set(key string, value interface{}) {
+	if om.values == nil {
+		om.values = make(map[string]interface{})
+	}
+	if _, exists := om.values[key]; !exists {
+		om.keys = append(om.keys, key)
+	}
+	om.values[key] = value
+}
+
+// Delete 删除键
+func (om *OrderedMap) Delete(key string) {
+	if _, exists := om.values[key]; !exists {
+		return
+	}
+	delete(om.values, key)
+	for i, k := range om.keys {
+		if k == key {
+			om.keys = append(om.keys[:i], om.keys[i+1:]...)
+			break
+		}
+	}
+}
+
+// Keys 按插入顺序返回所有键
+func (om *OrderedMap) Keys() []string {
+	return append([]string(nil), om.keys...)
+}
+
+// Len 返回键的数量
+func (om *OrderedMap) Len() int {
+	return len(om.keys)
+}
+
+// Range 按插入顺序遍历键值对，回调返回false时停止
+func (om *OrderedMap) Range(fn func(key string, value interface{}) bool) {
+	for _, k := range om.keys {
+		if !fn(k, om.values[k]) {
+			return
+		}
+	}
+}
+
+// ToMap 转换为普通map，嵌套的OrderedMap同样被转换
+func (om *OrderedMap) ToMap() map[string]interface{} {
+	m := make(map[string]interface{}, len(om.keys))
+	for _, k := range om.keys {
+		m[k] = plainValue(om.values[k])
+	}
+	return m
+}
+
+// MarshalJSON 按键的顺序编码为JSON对象
+func (om *OrderedMap) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	for i, k := range om.keys {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		key, err := json.Marshal(k)
+		if err != nil {
+			return nil, err
+		}
+		value, err := json.Marshal(om.values[k])
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(key)
+		buf.WriteByte(':')
+		buf.Write(value)
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
+
+// MarshalYAML 按键的顺序编码为YAML mapping
+func (om *OrderedMap) MarshalYAML() (interface{}, error) {
+	node := &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+	for _, k := range om.keys {
+		key, value := &yaml.Node{}, &yaml.Node{}
+		if err := key.Encode(k); err != nil {
+			return nil, err
+		}
+		if err := value.Encode(om.values[k]); err != nil {
+			return nil, err
+		}
+		node.Content = append(node.Content, key, value)
+	}
+	return node, nil
+}
+
+// plainValue 将值中嵌套的OrderedMap转换为普通map
+func plainValue(value interface{}) interface{} {
+	switch v := value.(type) {
+	case *OrderedMap:
+		return v.ToMap()
+	case []interface{}:
+		items := make([]interface{}, len(v))
+		for i, item := range v {
+			items[i] = plainValue(item)
+		}
+		return items
+	}
+	return value
+}
@@ -0,0 +1,22 @@
+package aiyaml
+
+import (
+	"fmt"
+)
+
+// ParseError 带位置信息的解析错误。严格模式下作为错误返回，
+// 容错模式下解析不会失败，错误作为警告记录在ParseResult.Warnings中
+type ParseError struct {
+	Line    int    // 行号，从1开始
+	Column  int    // 列号，从1开始
+	Snippet string // 出错的原始内容
+	Reason  string // 错误原因
+}
+
+// Error 返回带位置信息的描述
+func (e *ParseError) Error() string {
+	if e.Snippet == "" {
+		return fmt.Sprintf("第%d行第%d列: %s", e.Line, e.Column, e.Reason)
+	}
+	return fmt.Sprintf("第%d行第%d列: %s: %q", e.Line, e.Column, e.Reason, e.Snippet)
+}
@@ -0,0 +1,185 @@
+package aiyaml
+
+import (
+	"errors"
+	"fmt"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ErrRootNotMapping 根节点不是mapping，无法转换为map
+var ErrRootNotMapping = errors.New("YAML根节点不是mapping")
+
+// ParseResult 解析结果，根节点可以是mapping、sequence或标量
+type ParseResult struct {
+	// Value 选中文档的根节点值：map[string]interface{}（启用OrderedMaps时为*OrderedMap）、
+	// []interface{}、标量，空文档为nil
+	Value interface{}
+	// Documents 所有文档的根节点值，按出现顺序排列
+	Documents []interface{}
+	// Warnings 容错模式下解析时发现的问题，严格模式下第一个问题会作为错误返回
+	Warnings []*ParseError
+	// Path 得到结果所使用的解析方式
+	Path ParsePath
+	// StrictError 混合解析时yaml.v3的解析错误，即回退到容错解析器的原因
+	StrictError error
+	// Normalizations 启用规范化时的规范化报告，未启用时为nil
+	Normalizations *NormalizeReport
+	// Repairs 启用修复引擎时的修复报告，未启用时为nil
+	Repairs *RepairReport
+	// Incomplete 输出可能被截断（如达到max_tokens），结果是尽力解析得到的
+	Incomplete bool
+	// Tags 带有显式标签的值的位置（JSON Pointer，规则与Partial相同）和标签，没有标签时为nil。
+	// 未知标签不影响值的解析，只记录在这里
+	Tags map[string]string
+	// Comments 启用WithComments时值的注释，键为以 "." 连接的路径（如 "settings.timeout"、"steps.0"），
+	// 根节点为空字符串，多文档时以文档序号开头；未启用或没有注释时为nil
+	Comments map[string]Comment
+	// Partial 值可能不完整的位置，使用JSON Pointer表示（如 "/steps/2/desc"）。
+	// 单文档时相对于Value，多文档时相对于Documents（以文档序号开头）
+	Partial []string
+
+	textRoots []bool // 每个文档的根节点是否为容错模式下视为说明文字的普通字符串
+	textValue bool   // 选中文档的根节点是否为说明文字
+}
+
+// Map 返回mapping形式的根节点，*OrderedMap会被转换为普通map
+func (r *ParseResult) Map() (map[string]interface{}, bool) {
+	switch v := r.Value.(type) {
+	case map[string]interface{}:
+		return v, true
+	case *OrderedMap:
+		return v.ToMap(), true
+	}
+	return nil, false
+}
+
+// OrderedMap 返回保留键顺序的根节点，只有启用OrderedMaps时才可用
+func (r *ParseResult) OrderedMap() (*OrderedMap, bool) {
+	m, ok := r.Value.(*OrderedMap)
+	return m, ok
+}
+
+// Sequence 返回sequence形式的根节点
+func (r *ParseResult) Sequence() ([]interface{}, bool) {
+	s, ok := r.Value.([]interface{})
+	return s, ok
+}
+
+// IsScalar 判断根节点是否为标量（包括空文档）
+func (r *ParseResult) IsScalar() bool {
+	switch r.Value.(type) {
+	case map[string]interface{}, *OrderedMap, []interface{}:
+		return false
+	}
+	return true
+}
+
+// rootMap 将选中文档的根节点值转换为map
+func (r *ParseResult) rootMap() (map[string]interface{}, error) {
+	return rootToMap(r.Value, r.textValue)
+}
+
+// rootOrderedMap 将选中文档的根节点值转换为OrderedMap
+func (r *ParseResult) rootOrderedMap() (*OrderedMap, error) {
+	if m, ok := r.Value.(*OrderedMap); ok {
+		return m, nil
+	}
+	if err := rootError(r.Value, r.textValue); err != nil {
+		return nil, err
+	}
+	return NewOrderedMap(), nil
+}
+
+// documentMaps 将所有文档转换为map
+func (r *ParseResult) documentMaps() ([]map[string]interface{}, error) {
+	documents := make([]map[string]interface{}, 0, len(r.Documents))
+	for i, doc := range r.Documents {
+		m, err := rootToMap(doc, r.textRoots[i])
+		if err != nil {
+			return nil, err
+		}
+		documents = append(documents, m)
+	}
+	return documents, nil
+}
+
+// rootToMap 将根节点值转换为map：空文档和说明文字返回空map，sequence和其他标量返回ErrRootNotMapping
+func rootToMap(value interface{}, text bool) (map[string]interface{}, error) {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		return v, nil
+	case *OrderedMap:
+		return v.ToMap(), nil
+	}
+	if err := rootError(value, text); err != nil {
+		return nil, err
+	}
+	return make(map[string]interface{}), nil
+}
+
+// rootError 返回非mapping根节点无法转换为map的原因，空文档和说明文字（text）可以视为空map，返回nil
+func rootError(value interface{}, text bool) error {
+	switch value.(type) {
+	case nil:
+		return nil
+	case []interface{}:
+		return fmt.Errorf("%w: 根节点为sequence", ErrRootNotMapping)
+	}
+	if text {
+		return nil
+	}
+	return fmt.Errorf("%w: 根节点为标量", ErrRootNotMapping)
+}
+
+// isTextRoot 判断根节点是否为没有任何YAML结构的说明文字：没有引号、标签和锚点，
+// 也不是数字、布尔值或null的普通标量
+func isTextRoot(n *yaml.Node) bool {
+	if n == nil || n.Kind != yaml.ScalarNode || n.Style != 0 || n.Anchor != "" {
+		return false
+	}
+	_, ok := resolveScalar(n.Value).(string)
+	return ok
+}
+
+// selectedIndex 返回selectDocument选中（或作为非mapping结果返回）的文档序号
+func selectedIndex(count int, selector DocumentSelector) int {
+	if selector == DocumentFirst {
+		return 0
+	}
+	return count - 1
+}
+
+// selectDocument 按选择方式返回文档的根节点值。DocumentMerge在所有文档都是mapping时合并它们，
+// 否则返回最后一个文档
+func selectDocument(docs []interface{}, selector DocumentSelector) interface{} {
+	if len(docs) == 0 {
+		return nil
+	}
+	switch selector {
+	case DocumentFirst:
+		return docs[0]
+	case DocumentLast:
+		return docs[len(docs)-1]
+	}
+	merged := NewOrderedMap()
+	for _, doc := range docs {
+		switch m := doc.(type) {
+		case *OrderedMap:
+			m.Range(func(k string, v interface{}) bool {
+				merged.Set(k, v)
+				return true
+			})
+		case map[string]interface{}:
+			for k, v := range m {
+				merged.Set(k, v)
+			}
+		default:
+			return docs[len(docs)-1]
+		}
+	}
+	if _, ok := docs[0].(*OrderedMap); ok {
+		return merged
+	}
+	return merged.values
+}
@@ -0,0 +1,174 @@
+package aiyaml
+
+// ParserOptions 解析器配置
+type ParserOptions struct {
+	// TypedScalars 为true时按YAML 1.2 core schema解析普通标量（int64、float64、bool、nil），
+	// 为false时所有值都保留为字符串
+	TypedScalars bool
+	// MaxAliases 单次解析允许展开的别名次数上限，<=0时使用默认值
+	MaxAliases int
+	// MaxAliasNodes 通过别名展开产生的节点总数上限，<=0时使用默认值
+	MaxAliasNodes int
+	// DiagnosticHandler 接收解析过程中的警告，为nil时只记录日志
+	DiagnosticHandler func(*ParseError)
+	// DocumentSelector 多文档输入时LinesToMap返回的文档
+	DocumentSelector DocumentSelector
+	// TabWidth 计算缩进时制表符的宽度（列），<=0时使用默认值2
+	TabWidth int
+	// Strict 为true时遇到无法解析或被跳过的内容返回*ParseError，为false时作为警告记录
+	Strict bool
+	// OrderedMaps 为true时解析结果中的mapping（包括嵌套的mapping）为*OrderedMap，保留键的顺序
+	OrderedMaps bool
+	// DuplicateKeyPolicy 同一mapping中出现重复键时的处理方式
+	DuplicateKeyPolicy DuplicateKeyPolicy
+	// HybridParsing 为true时先用yaml.v3解析完整文档，失败后再使用容错解析器
+	HybridParsing bool
+	// Repair 为true时在解析之前运行修复引擎
+	Repair bool
+	// RepairRules 修复引擎启用的规则，为空时启用全部规则
+	RepairRules []RepairRule
+	// TypedEmpties 为true时没有值也没有子节点的键（key:）解析为空mapping，为false时为nil
+	TypedEmpties bool
+	// Normalize 为true时在行分类之前规范化Unicode字符和空白（批量解析和流式处理都生效）
+	Normalize bool
+	// NormalizeRules 启用的规范化规则，为空时启用全部规则
+	NormalizeRules []NormalizeRule
+	// Comments 为true时在ParseResult.Comments中按路径返回每个值的头注释、行尾注释和尾注释
+	Comments bool
+	// FinishReason 模型返回的结束原因（如 "stop"、"length"、"max_tokens"），表示达到长度上限时，
+	// 即使输出不在代码块中，末尾的空值和块标量也视为可能被截断
+	FinishReason string
+}
+
+// 别名展开的默认上限，防止恶意响应通过嵌套别名造成指数级膨胀
+const (
+	defaultMaxAliases    = 1000
+	defaultMaxAliasNodes = 100000
+)
+
+// ParserOption 解析器选项
+type ParserOption func(*ParserOptions)
+
+// WithTypedScalars 启用标量类型解析
+func WithTypedScalars() ParserOption {
+	return func(o *ParserOptions) {
+		o.TypedScalars = true
+	}
+}
+
+// WithAliasLimits 设置别名展开上限，参数<=0时使用默认值
+func WithAliasLimits(maxAliases, maxAliasNodes int) ParserOption {
+	return func(o *ParserOptions) {
+		o.MaxAliases = maxAliases
+		o.MaxAliasNodes = maxAliasNodes
+	}
+}
+
+// WithDiagnosticHandler 设置解析警告回调
+func WithDiagnosticHandler(handler func(*ParseError)) ParserOption {
+	return func(o *ParserOptions) {
+		o.DiagnosticHandler = handler
+	}
+}
+
+// WithDocumentSelector 设置多文档输入时LinesToMap返回的文档
+func WithDocumentSelector(selector DocumentSelector) ParserOption {
+	return func(o *ParserOptions) {
+		o.DocumentSelector = selector
+	}
+}
+
+// WithTabWidth 设置计算缩进时制表符的宽度，参数<=0时使用默认值
+func WithTabWidth(width int) ParserOption {
+	return func(o *ParserOptions) {
+		o.TabWidth = width
+	}
+}
+
+// WithStrictMode 启用严格模式
+func WithStrictMode() ParserOption {
+	return func(o *ParserOptions) {
+		o.Strict = true
+	}
+}
+
+// WithOrderedMaps 解析结果中的mapping使用*OrderedMap
+func WithOrderedMaps() ParserOption {
+	return func(o *ParserOptions) {
+		o.OrderedMaps = true
+	}
+}
+
+// WithDuplicateKeyPolicy 设置重复键的处理方式
+func WithDuplicateKeyPolicy(policy DuplicateKeyPolicy) ParserOption {
+	return func(o *ParserOptions) {
+		o.DuplicateKeyPolicy = policy
+	}
+}
+
+// WithHybridParsing 启用混合解析：格式正确的响应由yaml.v3解析，只有yaml.v3失败时才使用容错解析器
+func WithHybridParsing() ParserOption {
+	return func(o *ParserOptions) {
+		o.HybridParsing = true
+	}
+}
+
+// WithRepair 在解析之前运行修复引擎，只启用指定的规则，未指定规则时启用全部规则
+func WithRepair(rules ...RepairRule) ParserOption {
+	return func(o *ParserOptions) {
+		o.Repair = true
+		o.RepairRules = append([]RepairRule(nil), rules...)
+	}
+}
+
+// WithTypedEmpties 没有值也没有子节点的键（key:）解析为空mapping而不是nil，
+// 显式的 ~ 和 null 仍为nil
+func WithTypedEmpties() ParserOption {
+	return func(o *ParserOptions) {
+		o.TypedEmpties = true
+	}
+}
+
+// WithNormalization 在行分类之前规范化全角冒号、不换行空格、BOM、零宽字符、弯引号和\r\n行尾，
+// 只启用指定的规则，未指定规则时启用全部规则
+func WithNormalization(rules ...NormalizeRule) ParserOption {
+	return func(o *ParserOptions) {
+		o.Normalize = true
+		o.NormalizeRules = append([]NormalizeRule(nil), rules...)
+	}
+}
+
+// WithComments 保留注释：ParseResult.Comments按路径（如 "settings.timeout"）返回每个值的注释
+func WithComments() ParserOption {
+	return func(o *ParserOptions) {
+		o.Comments = true
+	}
+}
+
+// WithFinishReason 设置模型返回的结束原因，为 "length" 或 "max_tokens" 时末尾的空值和块标量标记为可能被截断。
+// EventProcessor会从事件的FinishReason字段中自动读取
+func WithFinishReason(reason string) ParserOption {
+	return func(o *ParserOptions) {
+		o.FinishReason = reason
+	}
+}
+
+// newParserOptions 根据选项创建解析器配置
+func newParserOptions(opts ...ParserOption) ParserOptions {
+	var options ParserOptions
+	for _, opt := range opts {
+		if opt != nil {
+			opt(&options)
+		}
+	}
+	if options.MaxAliases <= 0 {
+		options.MaxAliases = defaultMaxAliases
+	}
+	if options.MaxAliasNodes <= 0 {
+		options.MaxAliasNodes = defaultMaxAliasNodes
+	}
+	if options.TabWidth <= 0 {
+		options.TabWidth = defaultTabWidth
+	}
+	return options
+}
@@ -2,6 +2,8 @@ package aiyaml
 
 import (
 	"context"
+
+	"gopkg.in/yaml.v3"
 )
 
 // Processor 主处理器，整合所有功能模块
@@ -14,11 +16,11 @@ type Processor struct {
 }
 
 // NewProcessor 创建新的处理器
-func NewProcessor(logger Logger) *Processor {
+func NewProcessor(logger Logger, opts ...ParserOption) *Processor {
 	return &Processor{
-		eventProcessor: NewEventProcessor(logger),
-		yamlParser:     NewYAMLParser(logger),
-		stringUtils:    NewStringUtils(),
+		eventProcessor: NewEventProcessor(logger, opts...),
+		yamlParser:     NewYAMLParser(logger, opts...),
+		stringUtils:    newStringUtilsWithOptions(newParserOptions(opts...)),
 		regexPatterns:  NewYAMLRegexPatterns(),
 		logger:         logger,
 	}
@@ -29,11 +31,56 @@ func (p *Processor) ProcessAIResponseEvents(ctx context.Context, eventChan chan
 	return p.eventProcessor.ProcessAIResponseEvents(ctx, eventChan)
 }
 
+// ProcessAIResponseEventsOrdered 处理AI响应事件流，返回保留键顺序的结果
+func (p *Processor) ProcessAIResponseEventsOrdered(ctx context.Context, eventChan chan SSEvent) (*OrderedMap, error) {
+	return p.eventProcessor.ProcessAIResponseEventsOrdered(ctx, eventChan)
+}
+
+// ProcessAIResponseDocuments 处理AI响应事件流，返回所有文档
+func (p *Processor) ProcessAIResponseDocuments(ctx context.Context, eventChan chan SSEvent) ([]map[string]interface{}, error) {
+	return p.eventProcessor.ProcessAIResponseDocuments(ctx, eventChan)
+}
+
 // ProcessYAMLLines 直接处理YAML行（用于测试或独立使用）
 func (p *Processor) ProcessYAMLLines(ctx context.Context, lines []string) (map[string]interface{}, error) {
 	return p.yamlParser.LinesToMap(ctx, lines)
 }
 
+// ProcessYAMLLinesOrdered 直接处理YAML行，返回保留键顺序的结果
+func (p *Processor) ProcessYAMLLinesOrdered(ctx context.Context, lines []string) (*OrderedMap, error) {
+	return p.yamlParser.LinesToOrderedMap(ctx, lines)
+}
+
+// ParseAIResponseEvents 处理AI响应事件流，返回包含任意根节点的解析结果
+func (p *Processor) ParseAIResponseEvents(ctx context.Context, eventChan chan SSEvent) (*ParseResult, error) {
+	return p.eventProcessor.ParseAIResponseEvents(ctx, eventChan)
+}
+
+// ParseYAMLLines 直接解析YAML行，返回包含任意根节点的解析结果
+func (p *Processor) ParseYAMLLines(ctx context.Context, lines []string) (*ParseResult, error) {
+	return p.yamlParser.Parse(ctx, lines)
+}
+
+// ParseYAMLNode 直接解析YAML行，返回第一个文档的yaml.v3节点树
+func (p *Processor) ParseYAMLNode(ctx context.Context, lines []string) (*yaml.Node, error) {
+	return p.yamlParser.LinesToNode(ctx, lines)
+}
+
+// RepairYAMLLines 修复YAML行中常见的格式错误，返回修复后的行和修复报告
+func (p *Processor) RepairYAMLLines(lines []string) ([]string, *RepairReport) {
+	return newRepairer(p.logger, p.yamlParser.options).Repair(lines)
+}
+
+// NormalizeYAMLLines 规范化YAML行中的Unicode字符和空白，返回规范化后的行和规范化报告
+func (p *Processor) NormalizeYAMLLines(lines []string) ([]string, *NormalizeReport) {
+	return newNormalizer(p.logger, p.yamlParser.options).Normalize(lines)
+}
+
+// ProcessYAMLDocuments 直接处理包含多个文档的YAML行
+func (p *Processor) ProcessYAMLDocuments(ctx context.Context, lines []string) ([]map[string]interface{}, error) {
+	return p.yamlParser.LinesToDocuments(ctx, lines)
+}
+
 // GetStringUtils 获取字符串工具
 func (p *Processor) GetStringUtils() *StringUtils {
 	return p.stringUtils
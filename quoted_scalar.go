@@ -0,0 +1,205 @@
+package aiyaml
+
+import (
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// doubleQuotedEscapes 双引号标量中的单字符转义
+var doubleQuotedEscapes = map[byte]string{
+	'0':  "\x00",
+	'a':  "\a",
+	'b':  "\b",
+	't':  "\t",
+	'\t': "\t",
+	'n':  "\n",
+	'v':  "\v",
+	'f':  "\f",
+	'r':  "\r",
+	'e':  "\x1b",
+	' ':  " ",
+	'"':  "\"",
+	'/':  "/",
+	'\\': "\\",
+	'N':  "\u0085",
+	'_':  "\u00a0",
+	'L':  "\u2028",
+	'P':  "\u2029",
+}
+
+// forEachUnquoted 依次回调引号标量之外的每个字节位置，回调返回false时停止。
+// 引号只有出现在标量开头（行首、空白或流式指示符之后）时才被视为引号标量的开始
+func forEachUnquoted(text string, fn func(i int) bool) {
+	for i := 0; i < len(text); i++ {
+		ch := text[i]
+		if (ch == '"' || ch == '\'') && isScalarStart(text, i) {
+			end := quotedEnd(text, i)
+			if end < 0 {
+				return
+			}
+			i = end
+			continue
+		}
+		if !fn(i) {
+			return
+		}
+	}
+}
+
+// isScalarStart 判断位置i是否可能是一个标量的开头
+func isScalarStart(text string, i int) bool {
+	if i == 0 {
+		return true
+	}
+	switch text[i-1] {
+	case ' ', '\t', '[', '{', ',':
+	default:
+		return false
+	}
+	prev := strings.TrimRight(text[:i], " \t")
+	if prev == "" {
+		return true
+	}
+	switch prev[len(prev)-1] {
+	case '[', '{', ',', ':', '-', '?':
+		return true
+	}
+	return false
+}
+
+// quotedEnd 返回从start开始的引号标量的结束引号位置，未闭合时返回-1
+func quotedEnd(text string, start int) int {
+	return scanQuoteEnd(text, text[start], start+1)
+}
+
+// scanQuoteEnd 从from开始查找quote对应的结束引号
+func scanQuoteEnd(text string, quote byte, from int) int {
+	for i := from; i < len(text); i++ {
+		switch {
+		case quote == '"' && text[i] == '\\':
+			i++
+		case text[i] == quote:
+			if quote == '\'' && i+1 < len(text) && text[i+1] == '\'' {
+				i++
+				continue
+			}
+			return i
+		}
+	}
+	return -1
+}
+
+// stripComment 去除引号之外、前面为空白的 # 注释
+func stripComment(text string) string {
+	cut := -1
+	forEachUnquoted(text, func(i int) bool {
+		if text[i] == '#' && (i == 0 || text[i-1] == ' ' || text[i-1] == '\t') {
+			cut = i
+			return false
+		}
+		return true
+	})
+	if cut < 0 {
+		return text
+	}
+	return strings.TrimRight(text[:cut], " \t")
+}
+
+// unquoteScalar 去除标量两端的引号并处理转义，非引号标量原样返回
+func unquoteScalar(raw string) string {
+	if !isQuotedScalar(raw) {
+		return raw
+	}
+	inner := foldQuotedLines(raw[1:len(raw)-1], raw[0] == '"')
+	if raw[0] == '\'' {
+		return strings.ReplaceAll(inner, "''", "'")
+	}
+	return unescapeDoubleQuoted(inner)
+}
+
+// foldQuotedLines 按YAML规则折叠跨行的引号标量：换行折叠为空格，空行保留为换行
+func foldQuotedLines(inner string, double bool) string {
+	if !strings.Contains(inner, "\n") {
+		return inner
+	}
+	lines := strings.Split(inner, "\n")
+	var sb strings.Builder
+	pendingBreaks := 0
+	for i, line := range lines {
+		if i > 0 {
+			line = strings.TrimLeft(line, " \t")
+		}
+		if i < len(lines)-1 {
+			line = strings.TrimRight(line, " \t")
+		}
+		if i > 0 && i < len(lines)-1 && line == "" {
+			pendingBreaks++
+			continue
+		}
+		if i > 0 {
+			prev := sb.String()
+			switch {
+			case pendingBreaks > 0:
+				sb.WriteString(strings.Repeat("\n", pendingBreaks))
+			case double && escapesLineBreak(prev):
+				// 行尾转义的换行直接连接
+				sb.Reset()
+				sb.WriteString(prev[:len(prev)-1])
+			default:
+				sb.WriteByte(' ')
+			}
+			pendingBreaks = 0
+		}
+		sb.WriteString(line)
+	}
+	return sb.String()
+}
+
+// escapesLineBreak 判断行尾的反斜杠是否转义换行：行尾连续的反斜杠为奇数个时，最后一个没有被转义
+func escapesLineBreak(line string) bool {
+	n := 0
+	for n < len(line) && line[len(line)-1-n] == '\\' {
+		n++
+	}
+	return n%2 == 1
+}
+
+// unescapeDoubleQuoted 处理双引号标量中的转义序列
+func unescapeDoubleQuoted(s string) string {
+	if !strings.Contains(s, "\\") {
+		return s
+	}
+	var sb strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] != '\\' || i+1 >= len(s) {
+			sb.WriteByte(s[i])
+			continue
+		}
+		i++
+		if escaped, ok := doubleQuotedEscapes[s[i]]; ok {
+			sb.WriteString(escaped)
+			continue
+		}
+		width := 0
+		switch s[i] {
+		case 'x':
+			width = 2
+		case 'u':
+			width = 4
+		case 'U':
+			width = 8
+		}
+		if width > 0 && i+width < len(s) {
+			if code, err := strconv.ParseUint(s[i+1:i+1+width], 16, 32); err == nil && utf8.ValidRune(rune(code)) {
+				sb.WriteRune(rune(code))
+				i += width
+				continue
+			}
+		}
+		// 无法识别的转义保留原文
+		sb.WriteByte('\\')
+		sb.WriteByte(s[i])
+	}
+	return sb.String()
+}
@@ -0,0 +1,308 @@
+package aiyaml
+
+import (
+	"regexp"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// RepairRule 修复规则的名称
+type RepairRule string
+
+// 修复引擎支持的规则，按下列顺序依次应用于每一行
+const (
+	// RepairMarkdownBullet 将Markdown列表标记（"* "、"+ "、"• "）替换为数组项标记"- "
+	RepairMarkdownBullet RepairRule = "markdown-bullet"
+	// RepairTabIndent 将缩进中的制表符按TabWidth替换为空格
+	RepairTabIndent RepairRule = "tab-indent"
+	// RepairSeqItemSpace 为紧跟内容的数组项标记补充空格（"-item" -> "- item"），只在相邻行同样是数组项（包括缺少空格的数组项）或上一行是没有值的键时生效
+	RepairSeqItemSpace RepairRule = "seq-item-space"
+	// RepairKeyValueSpace 为紧跟值的冒号补充空格（"key:value" -> "key: value"），URL、Windows路径和数组中的host:port不处理
+	RepairKeyValueSpace RepairRule = "key-value-space"
+	// RepairQuoteColonValue 为包含": "的普通值加上单引号（"title: a: b" -> "title: 'a: b'"）
+	RepairQuoteColonValue RepairRule = "quote-colon-value"
+	// RepairUnderIndent 缩进介于两个层级之间的行：上一行开始新的块时作为其子节点，否则与更深一层的兄弟节点对齐
+	RepairUnderIndent RepairRule = "under-indent"
+)
+
+// RepairRules 所有修复规则，WithRepair未指定规则时全部启用
+var RepairRules = []RepairRule{
+	RepairMarkdownBullet,
+	RepairTabIndent,
+	RepairSeqItemSpace,
+	RepairKeyValueSpace,
+	RepairQuoteColonValue,
+	RepairUnderIndent,
+}
+
+// RepairChange 修复引擎对一行所做的一次修改
+type RepairChange struct {
+	Line   int        // 行号，从1开始
+	Rule   RepairRule // 应用的规则
+	Before string     // 修改前的行
+	After  string     // 修改后的行
+}
+
+// RepairReport 修复报告，按行号顺序列出所有修改，同一行的多次修改按规则的应用顺序排列
+type RepairReport struct {
+	Changes []RepairChange
+}
+
+// Count 返回指定规则的修改次数
+func (r *RepairReport) Count(rule RepairRule) int {
+	n := 0
+	for _, c := range r.Changes {
+		if c.Rule == rule {
+			n++
+		}
+	}
+	return n
+}
+
+// markdownBulletPattern 匹配Markdown列表标记
+var markdownBulletPattern = regexp.MustCompile(`^([*+] |•\s*)`)
+
+// missingSpaceKeyPattern 匹配冒号后缺少空格的键值对
+var missingSpaceKeyPattern = regexp.MustCompile(`^(` + keyNamePattern + `):([^\s:/\\].*)$`)
+
+// Repairer 修复模型输出中常见的YAML格式错误，在解析之前逐行应用启用的规则。
+// 修复只修改行的内容，不增加或删除行，报告中的行号与解析警告的行号一致
+type Repairer struct {
+	logger   Logger
+	rules    map[RepairRule]bool
+	tabWidth int
+}
+
+// NewRepairer 创建修复引擎，通过WithRepair指定启用的规则，未指定时启用全部规则
+func NewRepairer(logger Logger, opts ...ParserOption) *Repairer {
+	return newRepairer(logger, newParserOptions(opts...))
+}
+
+// newRepairer 根据解析器配置创建修复引擎
+func newRepairer(logger Logger, options ParserOptions) *Repairer {
+	rules := options.RepairRules
+	if len(rules) == 0 {
+		rules = RepairRules
+	}
+	rp := &Repairer{logger: logger, rules: make(map[RepairRule]bool, len(rules)), tabWidth: options.TabWidth}
+	for _, rule := range rules {
+		rp.rules[rule] = true
+	}
+	return rp
+}
+
+// repairState 修复过程中跟踪的上下文
+type repairState struct {
+	lines       []string
+	levels      []int // 当前路径上各层块的缩进，严格递增
+	opener      int   // 上一行开始新块时键所在的列，否则为-1
+	blockIndent int   // 块标量所属行的缩进，>=0时跳过更深的内容行
+	flowDepth   int   // 跨行流式集合未闭合的括号层数
+	unit        int   // 文档的缩进单位
+}
+
+// Repair 修复yaml代码行，返回修复后的行（包含换行符的行被拆分为多行）和修复报告
+func (rp *Repairer) Repair(lines []string) ([]string, *RepairReport) {
+	var physical []string
+	for _, line := range lines {
+		for _, raw := range strings.Split(line, "\n") {
+			physical = append(physical, strings.TrimRight(raw, "\r"))
+		}
+	}
+	state := &repairState{lines: physical, opener: -1, blockIndent: -1, unit: detectIndentUnit(physical, rp.tabWidth)}
+	report := &RepairReport{}
+	for i, line := range physical {
+		text := strings.TrimSpace(line)
+		if isDocumentMarker(text) {
+			state.levels, state.opener, state.blockIndent, state.flowDepth = nil, -1, -1, 0
+			continue
+		}
+		if text == "" || strings.HasPrefix(text, "#") || strings.HasPrefix(text, "```") {
+			continue
+		}
+		if state.blockIndent >= 0 {
+			if indentColumns(line, rp.tabWidth) > state.blockIndent {
+				continue
+			}
+			state.blockIndent = -1
+		}
+		if state.flowDepth > 0 {
+			state.flowDepth += flowDepth(stripComment(text))
+			continue
+		}
+
+		for _, rule := range RepairRules {
+			if !rp.rules[rule] {
+				continue
+			}
+			fixed := rp.apply(rule, line, i, state)
+			if fixed == line {
+				continue
+			}
+			report.Changes = append(report.Changes, RepairChange{Line: i + 1, Rule: rule, Before: line, After: fixed})
+			rp.logger.Infof("yaml repair %s at line %d: %q -> %q", rule, i+1, line, fixed)
+			line = fixed
+		}
+		physical[i] = line
+		state.advance(line, rp.tabWidth)
+	}
+	return physical, report
+}
+
+// apply 对第i行应用单个规则，返回修改后的行
+func (rp *Repairer) apply(rule RepairRule, line string, i int, state *repairState) string {
+	indent := line[:len(line)-len(strings.TrimLeft(line, " \t"))]
+	text := strings.TrimSpace(line)
+	switch rule {
+	case RepairMarkdownBullet:
+		if m := markdownBulletPattern.FindString(text); m != "" && len(text) > len(m) {
+			return indent + "- " + text[len(m):]
+		}
+	case RepairTabIndent:
+		if strings.Contains(indent, "\t") {
+			return strings.Repeat(" ", indentColumns(line, rp.tabWidth)) + text
+		}
+	case RepairSeqItemSpace:
+		if isUnspacedSeqItem(text) && state.inSequence(i, indentColumns(line, rp.tabWidth), rp.tabWidth) {
+			return indent + "- " + text[1:]
+		}
+	case RepairKeyValueSpace:
+		prefix, content := splitSeqPrefix(text)
+		if isKeyLine(content) {
+			break
+		}
+		m := missingSpaceKeyPattern.FindStringSubmatch(content)
+		if m == nil || (prefix != "" && isDigits(m[2])) {
+			break
+		}
+		return indent + prefix + m[1] + ": " + m[2]
+	case RepairQuoteColonValue:
+		prefix, content := splitSeqPrefix(text)
+		key, rest, ok := splitKeyValue(content)
+		if !ok {
+			break
+		}
+		value := stripComment(rest)
+		if value == "" || strings.ContainsAny(value[:1], "\"'[{|>&*!%@`") || !isKeyLine(value) {
+			break
+		}
+		return indent + prefix + key + ": '" + strings.ReplaceAll(value, "'", "''") + "'" + rest[len(value):]
+	case RepairUnderIndent:
+		_, content := splitSeqPrefix(text)
+		if !isCollectionLine(text) && !isCollectionLine(content) {
+			break
+		}
+		if column, ok := state.alignedIndent(indentColumns(line, rp.tabWidth)); ok {
+			return strings.Repeat(" ", column) + text
+		}
+	}
+	return line
+}
+
+// advance 根据修复后的行更新缩进层级、块标量和流式集合状态
+func (s *repairState) advance(line string, tabWidth int) {
+	text := strings.TrimSpace(line)
+	prefix, content := splitSeqPrefix(text)
+	if !isCollectionLine(text) && !isCollectionLine(content) {
+		s.opener = -1
+		return
+	}
+	indent := indentColumns(line, tabWidth)
+	for len(s.levels) > 0 && s.levels[len(s.levels)-1] > indent {
+		s.levels = s.levels[:len(s.levels)-1]
+	}
+	if len(s.levels) == 0 || s.levels[len(s.levels)-1] != indent {
+		s.levels = append(s.levels, indent)
+	}
+	column := indent
+	if prefix != "" && content != "" {
+		column = contentColumn(line, tabWidth)
+		if isCollectionLine(content) {
+			s.levels = append(s.levels, column)
+		}
+	}
+
+	s.opener = -1
+	value := lineValue(line)
+	switch {
+	case content == "" || (value == "" && isKeyLine(content)):
+		s.opener = column
+	case isFlowStart(value) && flowDepth(value) > 0:
+		s.flowDepth = flowDepth(value)
+	default:
+		if _, ok := parseBlockScalarHeader(value); ok {
+			s.blockIndent = indent
+		}
+	}
+}
+
+// alignedIndent 缩进不属于任何已有层级且介于两个层级之间时，返回应对齐到的列
+func (s *repairState) alignedIndent(indent int) (int, bool) {
+	for k := 0; k+1 < len(s.levels); k++ {
+		if s.levels[k] < indent && indent < s.levels[k+1] {
+			if k+2 == len(s.levels) && s.opener == s.levels[k+1] {
+				// 上一行开始了新的块，该行是缩进不足的子节点
+				return s.opener + s.unit, true
+			}
+			return s.levels[k+1], true
+		}
+	}
+	return 0, false
+}
+
+// inSequence 判断第i行是否位于数组中：与其缩进相同的上一个或下一个有效行是数组项（包括同样缺少空格的数组项），
+// 或上一个有效行是缩进更浅、没有值的键（key:）
+func (s *repairState) inSequence(i, indent, tabWidth int) bool {
+	for _, step := range []int{-1, 1} {
+		for j := i + step; j >= 0 && j < len(s.lines); j += step {
+			text := strings.TrimSpace(s.lines[j])
+			if text == "" || strings.HasPrefix(text, "#") {
+				continue
+			}
+			other := indentColumns(s.lines[j], tabWidth)
+			if other == indent {
+				if isSeqItem(text) || markdownBulletPattern.MatchString(text) || isUnspacedSeqItem(text) {
+					return true
+				}
+			} else if step < 0 && other < indent {
+				_, content := splitSeqPrefix(text)
+				if _, rest, ok := splitKeyValue(content); ok && rest == "" {
+					return true
+				}
+			}
+			break
+		}
+	}
+	return false
+}
+
+// isUnspacedSeqItem 判断内容是否为"-"之后缺少空格的数组项（"-item"）
+func isUnspacedSeqItem(text string) bool {
+	return len(text) > 1 && text[0] == '-' && isSeqItemContent(text[1:])
+}
+
+// isSeqItemContent 判断"-"之后紧跟的内容是否像数组项（以字母开头），排除负数和"---"等
+func isSeqItemContent(rest string) bool {
+	ch, _ := utf8.DecodeRuneInString(rest)
+	return unicode.IsLetter(ch) || ch == '_' || ch == '"' || ch == '\''
+}
+
+// splitSeqPrefix 拆分行首的数组项标记（包括"- - "），返回标记和剩余内容
+func splitSeqPrefix(text string) (string, string) {
+	content := text
+	for isSeqItem(content) {
+		content = strings.TrimLeft(content[1:], " \t")
+	}
+	return text[:len(text)-len(content)], content
+}
+
+// isDigits 判断字符串是否只包含数字
+func isDigits(s string) bool {
+	for _, ch := range s {
+		if ch < '0' || ch > '9' {
+			return false
+		}
+	}
+	return s != ""
+}
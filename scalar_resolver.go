@@ -0,0 +1,76 @@
+package aiyaml
+
+import (
+	"math"
+	"regexp"
+	"strconv"
+)
+
+// YAML 1.2 core schema 数值匹配规则
+var (
+	coreIntPattern   = regexp.MustCompile(`^[-+]?[0-9]+$`)
+	coreOctPattern   = regexp.MustCompile(`^0o[0-7]+$`)
+	coreHexPattern   = regexp.MustCompile(`^0x[0-9a-fA-F]+$`)
+	coreFloatPattern = regexp.MustCompile(`^[-+]?(\.[0-9]+|[0-9]+(\.[0-9]*)?)([eE][-+]?[0-9]+)?$`)
+)
+
+// resolveScalar 按YAML 1.2 core schema解析普通标量，无法识别时返回原字符串
+func resolveScalar(value string) interface{} {
+	if isNullLiteral(value) {
+		return nil
+	}
+	switch value {
+	case "true", "True", "TRUE":
+		return true
+	case "false", "False", "FALSE":
+		return false
+	case ".inf", ".Inf", ".INF", "+.inf", "+.Inf", "+.INF":
+		return math.Inf(1)
+	case "-.inf", "-.Inf", "-.INF":
+		return math.Inf(-1)
+	case ".nan", ".NaN", ".NAN":
+		return math.NaN()
+	}
+
+	switch {
+	case coreIntPattern.MatchString(value):
+		if n, err := strconv.ParseInt(value, 10, 64); err == nil {
+			return n
+		}
+		// 超出int64范围时退化为浮点数
+		if f, err := strconv.ParseFloat(value, 64); err == nil {
+			return f
+		}
+	case coreOctPattern.MatchString(value):
+		if n, err := strconv.ParseInt(value[2:], 8, 64); err == nil {
+			return n
+		}
+	case coreHexPattern.MatchString(value):
+		if n, err := strconv.ParseInt(value[2:], 16, 64); err == nil {
+			return n
+		}
+	case coreFloatPattern.MatchString(value):
+		if f, err := strconv.ParseFloat(value, 64); err == nil {
+			return f
+		}
+	}
+	return value
+}
+
+// isNullLiteral 判断普通标量是否表示null：空值、~、null、Null、NULL
+func isNullLiteral(value string) bool {
+	switch value {
+	case "", "~", "null", "Null", "NULL":
+		return true
+	}
+	return false
+}
+
+// isQuotedScalar 判断是否为引号包裹的标量
+func isQuotedScalar(value string) bool {
+	if len(value) < 2 {
+		return false
+	}
+	first, last := value[0], value[len(value)-1]
+	return (first == '"' || first == '\'') && first == last
+}
@@ -0,0 +1,199 @@
+package aiyaml
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// TagResolver 自定义标签的解析函数。value为去除标签后的值：标量为原始文本（string），
+// sequence和mapping为解码后的集合
+type TagResolver func(value interface{}) (interface{}, error)
+
+// 全局标签注册表，解析器和流式处理共用
+var (
+	tagResolversMu sync.RWMutex
+	tagResolvers   = make(map[string]TagResolver)
+)
+
+// RegisterTag 注册自定义标签（如 "!duration"）的解析函数，对之后的所有解析生效，
+// 重复注册时覆盖，resolver为nil时取消注册
+func RegisterTag(tag string, resolver TagResolver) {
+	tagResolversMu.Lock()
+	defer tagResolversMu.Unlock()
+	if resolver == nil {
+		delete(tagResolvers, tag)
+		return
+	}
+	tagResolvers[tag] = resolver
+}
+
+// lookupTagResolver 查找已注册的标签解析函数
+func lookupTagResolver(tag string) (TagResolver, bool) {
+	tagResolversMu.RLock()
+	defer tagResolversMu.RUnlock()
+	resolver, ok := tagResolvers[tag]
+	return resolver, ok
+}
+
+// tagPattern 匹配值开头的标签：!<verbatim>、!!type、!local、!handle!suffix。
+// 标签名必须以字母开头，避免将 "!@#$%" 之类的普通文本当作标签
+var tagPattern = regexp.MustCompile(`^(!<[^<>\s]+>|!!?[A-Za-z][\w.~:/#@$&'()*+;=%-]*|![\w-]+![\w.~:/#@$&'()*+;=%-]+)(?:[ \t]+|$)`)
+
+// yamlTagPrefix 标准标签的完整前缀
+const yamlTagPrefix = "tag:yaml.org,2002:"
+
+// timestampFormats !!timestamp允许的格式，与yaml.v3一致
+var timestampFormats = []string{
+	"2006-1-2T15:4:5.999999999Z07:00",
+	"2006-1-2t15:4:5.999999999Z07:00",
+	"2006-1-2 15:4:5.999999999",
+	"2006-1-2",
+}
+
+// splitTag 拆分值开头的标签，返回规范化的标签和剩余内容，没有标签时返回空字符串和原值
+func splitTag(value string) (string, string) {
+	m := tagPattern.FindStringSubmatch(value)
+	if m == nil {
+		return "", value
+	}
+	return normalizeTag(m[1]), value[len(m[0]):]
+}
+
+// normalizeTag 将完整形式的标签转换为简写：!<tag:yaml.org,2002:str> -> !!str，!<!foo> -> !foo
+func normalizeTag(tag string) string {
+	if !strings.HasPrefix(tag, "!<") {
+		return tag
+	}
+	tag = tag[2 : len(tag)-1]
+	if strings.HasPrefix(tag, yamlTagPrefix) {
+		return "!!" + tag[len(yamlTagPrefix):]
+	}
+	return tag
+}
+
+// splitProperties 拆分值开头的锚点（&name）和标签，两者顺序任意，返回锚点名、标签和剩余内容
+func splitProperties(value string) (string, string, string) {
+	anchor, tag := "", ""
+	for {
+		if a, rest := splitAnchor(value); a != "" && anchor == "" {
+			anchor, value = a, rest
+			continue
+		}
+		if t, rest := splitTag(value); t != "" && tag == "" {
+			tag, value = t, rest
+			continue
+		}
+		return anchor, tag, value
+	}
+}
+
+// isTagged 判断节点是否带有显式标签
+func isTagged(n *yaml.Node) bool {
+	return n.Style&yaml.TaggedStyle != 0
+}
+
+// taggedScalar 按显式标签转换标量：标准标签按类型解析，已注册的标签交给解析函数，
+// 未知标签或解析失败时按没有标签的标量处理
+func (d *nodeDecoder) taggedScalar(n *yaml.Node) interface{} {
+	value, err := standardScalar(n.Tag, n.Value)
+	if err == errUnknownTag {
+		resolver, ok := lookupTagResolver(n.Tag)
+		if !ok {
+			return d.untaggedScalar(n)
+		}
+		value, err = resolver(n.Value)
+	}
+	if err != nil {
+		d.tagError(n, err)
+		return d.untaggedScalar(n)
+	}
+	return value
+}
+
+// taggedCollection 将已注册的标签解析函数应用于sequence或mapping，标准标签和未知标签保持原值
+func (d *nodeDecoder) taggedCollection(n *yaml.Node, value interface{}) interface{} {
+	resolver, ok := lookupTagResolver(n.Tag)
+	if !ok {
+		return value
+	}
+	resolved, err := resolver(value)
+	if err != nil {
+		d.tagError(n, err)
+		return value
+	}
+	return resolved
+}
+
+// tagError 记录标签解析失败的警告
+func (d *nodeDecoder) tagError(n *yaml.Node, err error) {
+	d.warnings = append(d.warnings, &ParseError{
+		Line:    n.Line,
+		Column:  n.Column,
+		Snippet: n.Tag + " " + n.Value,
+		Reason:  fmt.Sprintf("标签解析失败，按没有标签的值处理: %v", err),
+	})
+}
+
+// errUnknownTag 不是标准标量标签
+var errUnknownTag = errors.New("未知的标签")
+
+// standardScalar 按标准标签（!!str、!!int、!!float、!!bool、!!null、!!binary、!!timestamp）转换标量
+func standardScalar(tag, value string) (interface{}, error) {
+	switch tag {
+	case "!!str":
+		return value, nil
+	case "!!null":
+		return nil, nil
+	case "!!bool":
+		if b, ok := resolveScalar(value).(bool); ok {
+			return b, nil
+		}
+	case "!!int":
+		if n, ok := resolveScalar(value).(int64); ok {
+			return n, nil
+		}
+	case "!!float":
+		switch f := resolveScalar(value).(type) {
+		case float64:
+			return f, nil
+		case int64:
+			return float64(f), nil
+		}
+	case "!!binary":
+		data, err := base64.StdEncoding.DecodeString(strings.Join(strings.Fields(value), ""))
+		if err != nil {
+			return nil, err
+		}
+		return string(data), nil
+	case "!!timestamp":
+		for _, format := range timestampFormats {
+			if t, err := time.Parse(format, value); err == nil {
+				return t, nil
+			}
+		}
+	default:
+		return nil, errUnknownTag
+	}
+	return nil, fmt.Errorf("%q 不是有效的%s值", value, tag)
+}
+
+// collectTags 返回所有带显式标签的节点的JSON Pointer和标签，多文档时以文档序号开头
+func collectTags(roots []*yaml.Node) map[string]string {
+	var tags map[string]string
+	walkDocuments(roots, func(n *yaml.Node, path string) {
+		if isTagged(n) {
+			if tags == nil {
+				tags = make(map[string]string)
+			}
+			tags[path] = n.Tag
+		}
+	})
+	return tags
+}
@@ -0,0 +1,122 @@
+package aiyaml
+
+import (
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// isOpenOutput 判断输出是否在未闭合的代码块中结束（通常是达到max_tokens）：代码块标记的数量为奇数，
+// 且最后一个非空行不是文档结束标记（...）。fences为流式拼装时已经去除的代码块标记数量
+func isOpenOutput(lines []string, fences int) bool {
+	last := ""
+	for _, line := range lines {
+		for _, raw := range strings.Split(line, "\n") {
+			text := strings.TrimSpace(raw)
+			if text == "" {
+				continue
+			}
+			if strings.HasPrefix(text, "```") {
+				fences++
+			}
+			last = text
+		}
+	}
+	return fences%2 == 1 && !isDocumentEnd(last)
+}
+
+// isLengthFinish 判断结束原因是否表示生成达到了长度上限（OpenAI的 "length"、Anthropic的 "max_tokens"）
+func isLengthFinish(reason string) bool {
+	switch strings.ToLower(reason) {
+	case "length", "max_tokens":
+		return true
+	}
+	return false
+}
+
+// partialPaths 返回值可能不完整的节点的JSON Pointer：truncated中未闭合的引号和流式集合，
+// 以及输出在中途结束（open，在未闭合的代码块中结束或达到长度上限）时最后一个文档末尾的空值或块标量。
+// placeholders为替换无法解析的内容（如未定义的别名）的null节点，不视为被截断的值
+func partialPaths(roots, truncated, placeholders []*yaml.Node, open bool) []string {
+	targets := make(map[*yaml.Node]bool, len(truncated)+1)
+	for _, n := range truncated {
+		targets[n] = true
+	}
+	if open && len(roots) > 0 {
+		if leaf := lastLeaf(roots[len(roots)-1]); isCutValue(leaf) && !containsNode(placeholders, leaf) {
+			targets[leaf] = true
+		}
+	}
+	if len(targets) == 0 {
+		return nil
+	}
+	var paths []string
+	walkDocuments(roots, func(n *yaml.Node, path string) {
+		if targets[n] {
+			paths = append(paths, path)
+		}
+	})
+	return paths
+}
+
+// lastLeaf 返回按文档顺序最后一个块集合中的最后一个值
+func lastLeaf(n *yaml.Node) *yaml.Node {
+	for n != nil && (n.Kind == yaml.MappingNode || n.Kind == yaml.SequenceNode) && n.Style&yaml.FlowStyle == 0 && len(n.Content) > 0 {
+		n = n.Content[len(n.Content)-1]
+	}
+	return n
+}
+
+// containsNode 判断节点是否在列表中
+func containsNode(nodes []*yaml.Node, n *yaml.Node) bool {
+	for _, node := range nodes {
+		if node == n {
+			return true
+		}
+	}
+	return false
+}
+
+// isCutValue 判断文档末尾的值是否可能被截断：没有值的键或块标量
+func isCutValue(n *yaml.Node) bool {
+	if n == nil || n.Kind != yaml.ScalarNode {
+		return false
+	}
+	return n.Style&(yaml.LiteralStyle|yaml.FoldedStyle) != 0 || (n.Value == "" && n.Style == 0)
+}
+
+// walkDocuments 按文档顺序深度优先遍历每个文档的值节点（不包括键和别名），回调节点及其JSON Pointer。
+// 单文档时路径相对于根节点，多文档时以文档序号开头
+func walkDocuments(roots []*yaml.Node, fn func(n *yaml.Node, path string)) {
+	for i, root := range roots {
+		prefix := ""
+		if len(roots) > 1 {
+			prefix = "/" + strconv.Itoa(i)
+		}
+		walkNodes(root, prefix, fn)
+	}
+}
+
+// walkNodes 深度优先遍历节点及其子节点
+func walkNodes(n *yaml.Node, path string, fn func(n *yaml.Node, path string)) {
+	if n == nil || n.Kind == yaml.AliasNode {
+		return
+	}
+	fn(n, path)
+	switch n.Kind {
+	case yaml.MappingNode:
+		for i := 0; i+1 < len(n.Content); i += 2 {
+			walkNodes(n.Content[i+1], path+"/"+escapePointer(mappingKey(n.Content[i])), fn)
+		}
+	case yaml.SequenceNode:
+		for i, item := range n.Content {
+			walkNodes(item, path+"/"+strconv.Itoa(i), fn)
+		}
+	}
+}
+
+// escapePointer 按RFC 6901转义JSON Pointer中的键
+func escapePointer(key string) string {
+	return strings.ReplaceAll(strings.ReplaceAll(key, "~", "~0"), "/", "~1")
+}
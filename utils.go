@@ -1,30 +1,53 @@
 package aiyaml
 
 import (
-	"regexp"
 	"strings"
 )
 
+// keyNamePattern 普通键名：以字母（任意语言）或下划线开头，由字母、组合符号、数字、下划线和连字符组成
+const keyNamePattern = `[\p{L}_][\p{L}\p{M}\p{N}_-]*`
+
 // YAMLRegexPatterns YAML正则表达式模式
 type YAMLRegexPatterns struct {
-	KeyValuePattern     *regexp.Regexp
-	KeyValueWithContent *regexp.Regexp
+	// KeyValuePattern 匹配包含键的行，规则与KeyDetector.IsKeyLine一致
+	KeyValuePattern LineMatcher
+	// KeyValueWithContent 匹配键之后带有值的行，规则与KeyDetector.HasValue一致
+	KeyValueWithContent LineMatcher
+	// KeyDetector 与解析器一致的键检测器，流式行拼装使用它判断键值对
+	KeyDetector *KeyDetector
 }
 
-// NewYAMLRegexPatterns 创建YAML正则表达式模式
+// NewYAMLRegexPatterns 创建YAML正则表达式模式，匹配规则由KeyDetector提供
 func NewYAMLRegexPatterns() *YAMLRegexPatterns {
+	detector := NewKeyDetector()
 	return &YAMLRegexPatterns{
-		KeyValuePattern:     regexp.MustCompile(`^.*[a-zA-Z]+:.*`),
-		KeyValueWithContent: regexp.MustCompile(`^.*[a-zA-Z]+: .+`),
+		KeyValuePattern:     lineMatcherFunc(detector.IsKeyLine),
+		KeyValueWithContent: lineMatcherFunc(detector.HasValue),
+		KeyDetector:         detector,
 	}
 }
 
 // StringUtils 字符串工具
-type StringUtils struct{}
+type StringUtils struct {
+	// TabWidth 制表符宽度（列），制表符前进到下一个TabWidth整数倍的列，<=0时使用默认值2
+	TabWidth int
+	// IndentUnit 每一级缩进的列数，<=0时使用默认值2，可以通过DetectIndentUnit从文档中检测
+	IndentUnit int
+}
 
 // NewStringUtils 创建字符串工具
 func NewStringUtils() *StringUtils {
-	return &StringUtils{}
+	return &StringUtils{
+		TabWidth:   defaultTabWidth,
+		IndentUnit: defaultIndentUnit,
+	}
+}
+
+// newStringUtilsWithOptions 按解析器配置创建字符串工具，使缩进规则与解析器一致
+func newStringUtilsWithOptions(options ParserOptions) *StringUtils {
+	su := NewStringUtils()
+	su.TabWidth = options.TabWidth
+	return su
 }
 
 // CleanYAMLMarkers 清理YAML标记
@@ -37,13 +60,46 @@ func (su *StringUtils) CleanYAMLMarkers(line string) string {
 	return line
 }
 
-// CalculateIndent 计算缩进级别
+// CalculateIndent 计算缩进级别：行首空白的列数除以IndentUnit，行内的空白不影响结果
 func (su *StringUtils) CalculateIndent(line string) int {
-	indent := strings.Count(line, "\t")
-	if indent == 0 {
-		indent = strings.Count(line, "  ")
+	return su.IndentColumns(line) / su.indentUnit()
+}
+
+// IndentColumns 计算行首空白占用的列数
+func (su *StringUtils) IndentColumns(line string) int {
+	return indentColumns(line, su.tabWidth())
+}
+
+// DetectIndentUnit 检测文档使用的缩进单位（列数），如2、3或4
+func (su *StringUtils) DetectIndentUnit(lines []string) int {
+	return detectIndentUnit(lines, su.tabWidth())
+}
+
+// CheckIndentation 检查缩进中制表符和空格的混用，返回的诊断信息与解析器一致
+func (su *StringUtils) CheckIndentation(lines []string) []*ParseError {
+	return checkIndentation(lines)
+}
+
+// tabWidth 返回生效的制表符宽度
+func (su *StringUtils) tabWidth() int {
+	if su.TabWidth <= 0 {
+		return defaultTabWidth
 	}
-	return indent
+	return su.TabWidth
+}
+
+// indentUnit 返回生效的缩进单位
+func (su *StringUtils) indentUnit() int {
+	if su.IndentUnit <= 0 {
+		return defaultIndentUnit
+	}
+	return su.IndentUnit
+}
+
+// Normalize 使用全部规范化规则处理全角冒号、不换行空格、BOM、零宽字符、弯引号和\r\n行尾，
+// 返回规范化后的行和规范化报告
+func (su *StringUtils) Normalize(lines []string) ([]string, *NormalizeReport) {
+	return newNormalizer(NewDefaultLogger(), newParserOptions(WithTabWidth(su.tabWidth()))).Normalize(lines)
 }
 
 // IsArrayItem 判断是否为数组项
@@ -51,11 +107,8 @@ func (su *StringUtils) IsArrayItem(line string) bool {
 	return strings.HasPrefix(strings.TrimSpace(line), "- ")
 }
 
-// ParseKeyValue 解析键值对
+// ParseKeyValue 解析键值对，规则与YAMLParser一致（见KeyDetector）：忽略数组项标记和引号内的冒号，
+// 去除行内注释，键和值去除引号
 func (su *StringUtils) ParseKeyValue(line string) (string, string, bool) {
-	parts := strings.SplitN(line, ":", 2)
-	if len(parts) != 2 {
-		return "", "", false
-	}
-	return strings.TrimSpace(parts[0]), strings.TrimSpace(parts[1]), true
+	return NewKeyDetector().Split(line)
 }
@@ -2,15 +2,33 @@ package aiyaml
 
 import (
 	"context"
-	"strings"
+
+	"gopkg.in/yaml.v3"
 )
 
 // 为了保持向后兼容，保留原始函数名
 // ProcessAIResponseEvents 处理AI响应事件流
-func ProcessAIResponseEvents(ctx context.Context, eventChan chan SSEvent) (map[string]interface{}, error) {
-	processor := NewProcessor(NewDefaultLogger().WithContext(ctx))
-	var result []string
-	line := ""
+func ProcessAIResponseEvents(ctx context.Context, eventChan chan SSEvent, opts ...ParserOption) (map[string]interface{}, error) {
+	result, err := ParseAIResponseEvents(ctx, eventChan, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return result.rootMap()
+}
+
+// ProcessAIResponseEventsOrdered 处理AI响应事件流，返回保留键顺序的OrderedMap
+func ProcessAIResponseEventsOrdered(ctx context.Context, eventChan chan SSEvent, opts ...ParserOption) (*OrderedMap, error) {
+	result, err := ParseAIResponseEvents(ctx, eventChan, append(append([]ParserOption(nil), opts...), WithOrderedMaps())...)
+	if err != nil {
+		return nil, err
+	}
+	return result.rootOrderedMap()
+}
+
+// ParseAIResponseEvents 处理AI响应事件流，根节点可以是mapping、sequence或标量
+func ParseAIResponseEvents(ctx context.Context, eventChan chan SSEvent, opts ...ParserOption) (*ParseResult, error) {
+	processor := NewProcessor(NewDefaultLogger().WithContext(ctx), opts...)
+	assembler := newLineAssembler(processor.logger, processor.yamlParser.options)
 	allContent := ""
 	for event := range eventChan {
 		if ctx.Err() != nil {
@@ -21,46 +39,58 @@ func ProcessAIResponseEvents(ctx context.Context, eventChan chan SSEvent) (map[s
 			processor.logger.Error("event error", event.Err)
 			return nil, event.Err
 		}
-		line += string(event.Data)
+		assembler.Write(string(event.Data))
 		allContent += string(event.Data)
-		if strings.HasSuffix(line, "\n") || strings.HasSuffix(line, "\\n") {
-			processor.logger.Infof("line: %s\n", line)
-			preLine := ""
-			if len(result) > 0 {
-				preLine = result[len(result)-1]
-				processor.logger.Infof("preLine: %s", preLine)
-			}
-			if !processor.regexPatterns.KeyValuePattern.MatchString(line) && len(result) > 0 && (!strings.HasPrefix(strings.TrimSpace(line), "- ") || processor.regexPatterns.KeyValueWithContent.MatchString(preLine)) {
-				line = preLine + line
-				line = strings.TrimRight(line, "\n")
-				line = strings.TrimRight(line, "\\n")
-				result[len(result)-1] = line
-				line = ""
-				continue
-			}
-			tabCount := strings.Count(line, "\t")
-			processor.logger.Infof("tabCount: %d", tabCount)
-			line = processor.stringUtils.CleanYAMLMarkers(line)
-			if strings.TrimSpace(line) == "" {
-				continue
-			}
-			line = strings.TrimRight(line, "\n")
-			line = strings.TrimRight(line, "\\n")
-			result = append(result, line)
-			line = ""
-		}
-	}
-	line = processor.stringUtils.CleanYAMLMarkers(line)
-	if strings.TrimSpace(line) != "" {
-		result = append(result, line)
 	}
+	result := assembler.Lines()
 	processor.logger.Infof("allContent: \n%s", allContent)
 	processor.logger.Infof("result: %v", result)
-	return processor.ProcessYAMLLines(ctx, result)
+	parsed, err := processor.yamlParser.parse(ctx, result, assembler.Open())
+	if err != nil {
+		return nil, err
+	}
+	parsed.Normalizations = mergeNormalizeReports(assembler.Normalizations(), parsed.Normalizations)
+	return parsed, nil
 }
 
 // YamlLinesToMap 将yaml代码行转换为map（保持向后兼容）
-func YamlLinesToMap(ctx context.Context, lines []string) (map[string]interface{}, error) {
-	processor := NewProcessor(NewDefaultLogger().WithContext(ctx))
+func YamlLinesToMap(ctx context.Context, lines []string, opts ...ParserOption) (map[string]interface{}, error) {
+	processor := NewProcessor(NewDefaultLogger().WithContext(ctx), opts...)
 	return processor.ProcessYAMLLines(ctx, lines)
 }
+
+// ParseYAMLLines 解析yaml代码行，根节点可以是mapping、sequence或标量
+func ParseYAMLLines(ctx context.Context, lines []string, opts ...ParserOption) (*ParseResult, error) {
+	processor := NewProcessor(NewDefaultLogger().WithContext(ctx), opts...)
+	return processor.ParseYAMLLines(ctx, lines)
+}
+
+// YamlLinesToOrderedMap 将yaml代码行转换为保留键顺序的OrderedMap
+func YamlLinesToOrderedMap(ctx context.Context, lines []string, opts ...ParserOption) (*OrderedMap, error) {
+	processor := NewProcessor(NewDefaultLogger().WithContext(ctx), opts...)
+	return processor.ProcessYAMLLinesOrdered(ctx, lines)
+}
+
+// YamlLinesToNode 将yaml代码行解析为yaml.v3的节点树（第一个文档）
+func YamlLinesToNode(ctx context.Context, lines []string, opts ...ParserOption) (*yaml.Node, error) {
+	processor := NewProcessor(NewDefaultLogger().WithContext(ctx), opts...)
+	return processor.ParseYAMLNode(ctx, lines)
+}
+
+// RepairYAMLLines 修复yaml代码行中常见的格式错误，返回修复后的行和修复报告
+func RepairYAMLLines(ctx context.Context, lines []string, opts ...ParserOption) ([]string, *RepairReport) {
+	processor := NewProcessor(NewDefaultLogger().WithContext(ctx), opts...)
+	return processor.RepairYAMLLines(lines)
+}
+
+// NormalizeYAMLLines 规范化yaml代码行中的Unicode字符和空白，返回规范化后的行和规范化报告
+func NormalizeYAMLLines(ctx context.Context, lines []string, opts ...ParserOption) ([]string, *NormalizeReport) {
+	processor := NewProcessor(NewDefaultLogger().WithContext(ctx), opts...)
+	return processor.NormalizeYAMLLines(lines)
+}
+
+// YamlLinesToDocuments 将包含多个文档（以 --- 分隔）的yaml代码行转换为map列表
+func YamlLinesToDocuments(ctx context.Context, lines []string, opts ...ParserOption) ([]map[string]interface{}, error) {
+	processor := NewProcessor(NewDefaultLogger().WithContext(ctx), opts...)
+	return processor.ProcessYAMLDocuments(ctx, lines)
+}
@@ -2,111 +2,677 @@ package aiyaml
 
 import (
 	"context"
-	"regexp"
+	"errors"
+	"sort"
 	"strings"
+
+	"gopkg.in/yaml.v3"
 )
 
 // YAMLParser YAML解析器
 type YAMLParser struct {
-	logger Logger
+	logger  Logger
+	options ParserOptions
 }
 
 // NewYAMLParser 创建新的YAML解析器
-func NewYAMLParser(logger Logger) *YAMLParser {
+func NewYAMLParser(logger Logger, opts ...ParserOption) *YAMLParser {
 	return &YAMLParser{
-		logger: logger,
+		logger:  logger,
+		options: newParserOptions(opts...),
+	}
+}
+
+// Parse 解析yaml代码行，根节点可以是mapping、sequence或标量
+func (yp *YAMLParser) Parse(ctx context.Context, lines []string) (*ParseResult, error) {
+	return yp.parse(ctx, lines, isOpenOutput(lines, 0))
+}
+
+// parse 解析yaml代码行，open表示输出在未闭合的代码块中结束，此时文档末尾的空值和块标量可能被截断
+func (yp *YAMLParser) parse(ctx context.Context, lines []string, open bool) (*ParseResult, error) {
+	parsed, err := yp.parseNodes(ctx, lines, open)
+	if err != nil {
+		return nil, err
+	}
+	decoder := newNodeDecoder(&yp.options)
+	result := &ParseResult{
+		Documents:      make([]interface{}, 0, len(parsed.roots)),
+		Warnings:       parsed.warnings,
+		Path:           parsed.path,
+		StrictError:    parsed.strictError,
+		Normalizations: parsed.normalizations,
+		Repairs:        parsed.repairs,
+		Incomplete:     len(parsed.partial) > 0,
+		Partial:        parsed.partial,
+		Tags:           collectTags(parsed.roots),
+		textRoots:      make([]bool, len(parsed.roots)),
+	}
+	if yp.options.Comments {
+		result.Comments = collectComments(parsed.roots)
+	}
+	for i, doc := range parsed.roots {
+		// 容错模式下只有说明文字的响应按旧版本的行为视为空map，严格模式下返回ErrRootNotMapping
+		result.textRoots[i] = !yp.options.Strict && isTextRoot(doc)
+		value, err := decoder.decode(doc)
+		if err != nil {
+			yp.logger.Errorf("yaml decode error: %v", err)
+			return nil, err
+		}
+		result.Documents = append(result.Documents, value)
+	}
+	if len(decoder.warnings) > 0 {
+		yp.reportDiagnostics(decoder.warnings)
+		if yp.options.Strict {
+			return nil, decoder.warnings[0]
+		}
+		result.Warnings = append(result.Warnings, decoder.warnings...)
+	}
+	result.Value = selectDocument(result.Documents, yp.options.DocumentSelector)
+	if len(parsed.roots) > 0 {
+		result.textValue = result.textRoots[selectedIndex(len(parsed.roots), yp.options.DocumentSelector)]
 	}
+	return result, nil
 }
 
-// LinesToMap 将yaml代码行转换为map
+// LinesToMap 将yaml代码行转换为map，根节点为sequence或标量时返回ErrRootNotMapping
 func (yp *YAMLParser) LinesToMap(ctx context.Context, lines []string) (map[string]interface{}, error) {
-	re := regexp.MustCompile(`^.*[a-zA-Z]+: .*`)
+	result, err := yp.Parse(ctx, lines)
+	if err != nil {
+		return nil, err
+	}
+	return result.rootMap()
+}
 
-	type node struct {
-		value  interface{} // map[string]interface{} 或 []interface{}
-		key    string      // 当前key
-		indent int
+// LinesToOrderedMap 将yaml代码行转换为保留键顺序的OrderedMap，嵌套的mapping同样为*OrderedMap
+func (yp *YAMLParser) LinesToOrderedMap(ctx context.Context, lines []string) (*OrderedMap, error) {
+	ordered := *yp
+	ordered.options.OrderedMaps = true
+	result, err := ordered.Parse(ctx, lines)
+	if err != nil {
+		return nil, err
 	}
-	result := make(map[string]interface{})
-	stack := []node{{value: result, key: "", indent: -1}}
+	return result.rootOrderedMap()
+}
 
-	for i := 0; i < len(lines); i++ {
-		line := lines[i]
-		if strings.TrimSpace(line) == "" || strings.HasPrefix(strings.TrimSpace(line), "#") {
-			continue
+// parsedDocuments 解析得到的文档根节点和解析过程信息
+type parsedDocuments struct {
+	roots          []*yaml.Node
+	warnings       []*ParseError
+	path           ParsePath
+	strictError    error
+	normalizations *NormalizeReport
+	repairs        *RepairReport
+	partial        []string
+}
+
+// parseNodes 解析出每个文档的根节点并按位置顺序报告警告，严格模式下第一个警告作为错误返回。
+// 启用规范化和修复引擎时先依次处理输入行，启用混合解析时先尝试yaml.v3，失败后再使用容错解析器
+func (yp *YAMLParser) parseNodes(ctx context.Context, lines []string, open bool) (*parsedDocuments, error) {
+	parsed := &parsedDocuments{path: ParsePathTolerant}
+	if yp.options.Normalize {
+		lines, parsed.normalizations = newNormalizer(yp.logger, yp.options).Normalize(lines)
+	}
+	if yp.options.Repair {
+		lines, parsed.repairs = newRepairer(yp.logger, yp.options).Repair(lines)
+	}
+	var duplicates []*ParseError
+	if yp.options.HybridParsing {
+		roots, err := parseStrict(lines)
+		if err == nil {
+			parsed.roots, parsed.path = roots, ParsePathStrict
+			for _, root := range roots {
+				duplicates = append(duplicates, findDuplicateKeys(root)...)
+			}
+			parsed.warnings = duplicates
+		} else {
+			yp.logger.Infof("yaml.v3 parse failed, fallback to tolerant parser: %v", err)
+			parsed.strictError = err
+		}
+	}
+	var truncated, placeholders []*yaml.Node
+	if parsed.path == ParsePathTolerant {
+		lp := newLineParser(lines, yp.options.TabWidth)
+		parsed.roots = lp.parseDocuments()
+		parsed.warnings, duplicates, truncated, placeholders = lp.diagnostics, lp.duplicates, lp.truncated, lp.placeholders
+	}
+	parsed.partial = partialPaths(parsed.roots, truncated, placeholders, open || isLengthFinish(yp.options.FinishReason))
+	if len(parsed.partial) > 0 {
+		yp.logger.Infof("yaml output may be truncated, partial values: %v", parsed.partial)
+	}
+
+	sort.SliceStable(parsed.warnings, func(i, j int) bool {
+		a, b := parsed.warnings[i], parsed.warnings[j]
+		return a.Line < b.Line || (a.Line == b.Line && a.Column < b.Column)
+	})
+	yp.reportDiagnostics(parsed.warnings)
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if yp.options.DuplicateKeyPolicy == DuplicateKeyError && len(duplicates) > 0 {
+		return nil, duplicates[0]
+	}
+	if yp.options.Strict && len(parsed.warnings) > 0 {
+		return nil, parsed.warnings[0]
+	}
+	return parsed, nil
+}
+
+// reportDiagnostics 记录解析警告并交给回调处理
+func (yp *YAMLParser) reportDiagnostics(diagnostics []*ParseError) {
+	for _, d := range diagnostics {
+		yp.logger.Infof("yaml diagnostic: %s", d)
+		if yp.options.DiagnosticHandler != nil {
+			yp.options.DiagnosticHandler(d)
+		}
+	}
+}
+
+// parseLine 预处理后的YAML行
+type parseLine struct {
+	num     int    // 行号，从1开始
+	indent  int    // 内容起始列
+	text    string // 去除缩进、注释和行尾空白后的内容
+	raw     string // 原始行
+	comment string // 行尾注释或整行注释（包含#），已归属到节点后清空
+}
+
+// lineParser 基于行的容错解析器，每次解析创建一个实例
+type lineParser struct {
+	lines        []*parseLine
+	pos          int
+	anchors      map[string]*yaml.Node
+	diagnostics  []*ParseError
+	duplicates   []*ParseError // 重复键警告，同时包含在diagnostics中
+	truncated    []*yaml.Node  // 引号或流式集合直到输入结尾仍未闭合、值可能不完整的节点
+	placeholders []*yaml.Node  // 替换无法解析的内容（如未定义的别名）的null节点
+	tabWidth     int
+	source       []*parseLine    // 所有输入行，lines在多文档解析时只是其中一段
+	targets      []commentTarget // 当前文档中可以带尾注释的条目
+}
+
+// newLineParser 预处理输入行并创建解析器，缩进按tabWidth计算列数
+func newLineParser(lines []string, tabWidth int) *lineParser {
+	lp := &lineParser{anchors: make(map[string]*yaml.Node), tabWidth: tabWidth}
+	for _, line := range lines {
+		for _, raw := range strings.Split(line, "\n") {
+			raw = strings.TrimRight(raw, "\r")
+			trimmed := strings.TrimSpace(raw)
+			text := stripComment(trimmed)
+			comment := strings.TrimSpace(trimmed[len(text):])
+			if strings.HasPrefix(text, "```") {
+				// 代码块标记不属于YAML内容
+				text, comment = "", ""
+			}
+			lp.lines = append(lp.lines, &parseLine{
+				num:     len(lp.lines) + 1,
+				indent:  indentColumns(raw, tabWidth),
+				text:    text,
+				raw:     raw,
+				comment: comment,
+			})
 		}
-		indent := strings.Count(line, "\t")
-		if indent == 0 {
-			indent = strings.Count(line, "  ")
-		}
-		trimmedLine := strings.TrimSpace(line)
-		for len(stack) > 0 && indent <= stack[len(stack)-1].indent {
-			stack = stack[:len(stack)-1]
-		}
-		parent := stack[len(stack)-1]
-		if strings.HasPrefix(trimmedLine, "- ") && len(stack) > 1 {
-			// 直接操作父级map的key
-			if p, ok := stack[len(stack)-2].value.(map[string]interface{}); ok {
-				arr, _ := p[parent.key].([]interface{})
-				itemStr := strings.TrimPrefix(trimmedLine, "- ")
-				var newItem interface{}
-				if re.MatchString(itemStr) {
-					parts := strings.SplitN(itemStr, ":", 2)
-					k := strings.TrimSpace(parts[0])
-					v := strings.TrimSpace(parts[1])
-					item := map[string]interface{}{k: v}
-					newItem = item
-					if i+1 < len(lines) {
-						nextLine := lines[i+1]
-						nextIndent := strings.Count(nextLine, "\t")
-						if nextIndent == 0 {
-							nextIndent = strings.Count(nextLine, "  ")
-						}
-						if nextIndent > indent {
-							stack = append(stack, node{value: item, key: "", indent: indent})
-						}
-					}
-				} else {
-					newItem = itemStr
-				}
-				arr = append(arr, newItem)
-				p[parent.key] = arr
+	}
+	lp.source = lp.lines
+	raws := make([]string, len(lp.lines))
+	for i, l := range lp.lines {
+		raws[i] = l.raw
+	}
+	lp.diagnostics = append(lp.diagnostics, checkIndentation(raws)...)
+	return lp
+}
+
+// peek 跳过空行和注释行，返回下一个有效行
+func (lp *lineParser) peek() *parseLine {
+	for lp.pos < len(lp.lines) {
+		l := lp.lines[lp.pos]
+		if l.text != "" {
+			return l
+		}
+		lp.pos++
+	}
+	return nil
+}
+
+// parseDocument 解析单个文档，根节点可以是mapping、sequence或标量
+func (lp *lineParser) parseDocument() *yaml.Node {
+	l := lp.peek()
+	if l != nil && isPlainText(l.text) {
+		// 结构化内容之前的说明文字直接跳过，整个文档都是普通文本时作为根标量
+		if start := lp.firstStructuralLine(); start >= 0 {
+			for l := lp.peek(); l != nil && lp.pos < start; l = lp.peek() {
+				lp.skipLine(l, "YAML内容之前的说明文字已跳过")
 			}
+		} else {
+			return lp.parseNode(0)
+		}
+	}
+	if l = lp.peek(); l == nil {
+		return nil
+	}
+	if !isCollectionLine(l.text) {
+		node := lp.parseNode(0)
+		lp.skipRest("根节点之后无法归属的内容已跳过")
+		return node
+	}
+	if anchor, inner := splitAnchor(l.text); isSeqItem(inner) {
+		// 第一项之前的锚点属于整个sequence
+		l.text = inner
+		node := lp.parseSequence(l.indent)
+		lp.setAnchor(node, anchor)
+		lp.skipRest("根sequence之后无法归属的内容已跳过")
+		return node
+	}
+	root := &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map", Line: l.num, Column: l.indent + 1}
+	for l := lp.peek(); l != nil; l = lp.peek() {
+		anchor, inner := splitAnchor(l.text)
+		if isSeqItem(inner) || !isCollectionLine(l.text) {
+			lp.skipLine(l, "根级别无法归属的行已跳过")
 			continue
 		}
-		// 处理 key: value 或 key:
-		parts := strings.SplitN(trimmedLine, ":", 2)
-		if len(parts) != 2 {
+		l.text = inner
+		m := lp.parseMapping(l.indent)
+		if m == nil {
+			lp.skipLine(l, "根级别无法归属的行已跳过")
 			continue
 		}
-		key := strings.TrimSpace(parts[0])
-		value := strings.TrimSpace(parts[1])
-		if value == "" {
-			// 判断下一级是数组还是map
-			isArray := false
-			if i+1 < len(lines) {
-				nextLine := lines[i+1]
-				nextIndent := strings.Count(nextLine, "\t")
-				if nextIndent == 0 {
-					nextIndent = strings.Count(nextLine, "  ")
-				}
-				nextTrimmed := strings.TrimSpace(nextLine)
-				if nextTrimmed != "" && strings.HasPrefix(nextTrimmed, "- ") && nextIndent > indent {
-					isArray = true
-				}
-			}
-			if isArray {
-				newArr := []interface{}{}
-				parent.value.(map[string]interface{})[key] = newArr
-				stack = append(stack, node{value: newArr, key: key, indent: indent})
-			} else {
-				newMap := map[string]interface{}{}
-				parent.value.(map[string]interface{})[key] = newMap
-				stack = append(stack, node{value: newMap, key: key, indent: indent})
+		lp.setAnchor(m, anchor)
+		root.Content = append(root.Content, m.Content...)
+	}
+	return root
+}
+
+// firstStructuralLine 返回当前位置之后第一个数组项或键值对行的位置，不存在时返回-1
+func (lp *lineParser) firstStructuralLine() int {
+	for i := lp.pos; i < len(lp.lines); i++ {
+		if text := lp.lines[i].text; text != "" && isCollectionLine(text) {
+			return i
+		}
+	}
+	return -1
+}
+
+// parseNode 解析从下一行开始、缩进不小于minIndent的节点
+func (lp *lineParser) parseNode(minIndent int) *yaml.Node {
+	l := lp.peek()
+	if l == nil || l.indent < minIndent {
+		return nil
+	}
+	if isSeqItem(l.text) {
+		return lp.parseSequence(l.indent)
+	}
+	if isKeyLine(l.text) || isExplicitKey(l.text) {
+		return lp.parseMapping(l.indent)
+	}
+	lp.pos++
+	return lp.parseValue(l.text, l, minIndent-1)
+}
+
+// parseMapping 解析指定缩进的mapping
+func (lp *lineParser) parseMapping(indent int) *yaml.Node {
+	var node *yaml.Node
+	for l := lp.peek(); l != nil; l = lp.peek() {
+		if l.indent < indent {
+			break
+		}
+		if isSeqItem(l.text) {
+			if l.indent == indent {
+				break
 			}
-		} else {
-			parent.value.(map[string]interface{})[key] = value
+			lp.skipLine(l, "缩进更深但无法归属的数组项已跳过")
+			continue
+		}
+		if node == nil {
+			node = &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map", Line: l.num, Column: l.indent + 1}
+		}
+		if isExplicitKey(l.text) {
+			keyNode, value := lp.parseExplicitEntry(l, indent)
+			node.Content = append(node.Content, keyNode, value)
+			continue
 		}
+		key, rest, ok := splitKeyValue(l.text)
+		if !ok {
+			lp.skipLine(l, "无法识别的行已跳过")
+			continue
+		}
+		lp.pos++
+		keyNode := newKeyNode(key, l)
+		keyNode.HeadComment = lp.headComment(l)
+		lp.addCommentTarget(keyNode, l.num, l.indent)
+		value := lp.parseValue(rest, l, indent)
+		if rest == "" {
+			keyNode.LineComment = l.comment
+		} else if value != nil {
+			value.LineComment = l.comment
+		}
+		node.Content = append(node.Content, keyNode, value)
 	}
-	return result, nil
+	return node
+}
+
+// parseSequence 解析指定缩进的sequence
+func (lp *lineParser) parseSequence(indent int) *yaml.Node {
+	var node *yaml.Node
+	for l := lp.peek(); l != nil; l = lp.peek() {
+		if l.indent != indent || !isSeqItem(l.text) {
+			break
+		}
+		if node == nil {
+			node = &yaml.Node{Kind: yaml.SequenceNode, Tag: "!!seq", Line: l.num, Column: l.indent + 1}
+		}
+		item := lp.parseIndicatorNode(l, indent)
+		lp.addCommentTarget(item, l.num, indent)
+		node.Content = append(node.Content, item)
+	}
+	return node
+}
+
+// parseExplicitEntry 解析显式键（"? key"）及同一缩进上紧随其后的值（": value"），没有值时为null
+func (lp *lineParser) parseExplicitEntry(l *parseLine, indent int) (*yaml.Node, *yaml.Node) {
+	key := lp.parseIndicatorNode(l, indent)
+	lp.addCommentTarget(key, l.num, indent)
+	if next := lp.peek(); next != nil && next.indent == indent && isExplicitValue(next.text) {
+		return key, lp.parseIndicatorNode(next, indent)
+	}
+	return key, &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!null", Line: key.Line, Column: key.Column}
+}
+
+// parseIndicatorNode 解析单字符指示符（数组项"-"、显式键"?"、显式值":"）之后的节点：
+// 内容为空时解析缩进更深的子节点，内容为数组项或键值对时视为位于更深一列的新行继续解析
+func (lp *lineParser) parseIndicatorNode(l *parseLine, indent int) *yaml.Node {
+	content := strings.TrimLeft(l.text[1:], " \t")
+	var item *yaml.Node
+	switch {
+	case content == "":
+		head, comment := lp.headComment(l), l.comment
+		lp.pos++
+		item = lp.parseNode(indent + 1)
+		if item == nil {
+			item = &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!null", Line: l.num, Column: l.indent + 1}
+		}
+		item.HeadComment, item.LineComment = head, comment
+	case isCollectionLine(content) && (l.text[0] != '-' || !isDriveLetter(content)):
+		// 将指示符之后的内容视为位于更深一列的新行继续解析
+		head := lp.headComment(l)
+		anchor, inner := splitAnchor(content)
+		l.indent += len(l.text) - len(inner)
+		l.text = inner
+		item = lp.parseNode(l.indent)
+		lp.setAnchor(item, anchor)
+		if item != nil {
+			item.HeadComment = head
+		}
+	default:
+		head := lp.headComment(l)
+		lp.pos++
+		item = lp.parseValue(content, l, indent)
+		item.HeadComment, item.LineComment = head, l.comment
+	}
+	return item
+}
+
+// parseValue 解析键或数组项之后的值（包括锚点和别名），parentIndent为所属节点的缩进
+func (lp *lineParser) parseValue(rest string, l *parseLine, parentIndent int) *yaml.Node {
+	column := l.indent + len(l.text) - len(rest) + 1
+	if name, ok := parseAlias(rest); ok {
+		return lp.newAlias(name, l.num, column)
+	}
+	anchor, tag, rest := splitProperties(rest)
+	node := lp.parseValueContent(rest, l, parentIndent)
+	if tag != "" && node != nil {
+		node.Tag, node.Style = tag, node.Style|yaml.TaggedStyle
+	}
+	if (anchor != "" || tag != "") && node != nil {
+		// 与yaml.v3一致，带锚点或标签的节点从锚点或标签处开始
+		node.Line, node.Column = l.num, column
+	}
+	lp.setAnchor(node, anchor)
+	return node
+}
+
+// parseValueContent 解析去除锚点和标签后的值
+func (lp *lineParser) parseValueContent(rest string, l *parseLine, parentIndent int) *yaml.Node {
+	column := l.indent + len(l.text) - len(rest) + 1
+	if rest == "" {
+		next := lp.peek()
+		if next != nil && next.indent > parentIndent {
+			return lp.parseNode(parentIndent + 1)
+		}
+		if next != nil && next.indent == parentIndent && isSeqItem(next.text) && isKeyLine(l.text) {
+			// 与键缩进相同的数组项（无缩进的sequence）属于该键
+			return lp.parseSequence(parentIndent)
+		}
+		// 没有子节点的空值为null，与yaml.v3一致
+		return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!null", Line: l.num, Column: column}
+	}
+	if header, ok := parseBlockScalarHeader(rest); ok {
+		value := lp.parseBlockScalar(header, parentIndent)
+		style := yaml.LiteralStyle
+		if header.folded {
+			style = yaml.FoldedStyle
+		}
+		return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Style: style, Value: value, Line: l.num, Column: column}
+	}
+	if isFlowStart(rest) {
+		text := lp.joinFlowLines(rest)
+		node, err := lp.parseFlowCollection(text, l.num, column)
+		if err == nil {
+			return node
+		}
+		// 无法解析的流式集合按普通字符串处理
+		var parseErr *ParseError
+		if errors.As(err, &parseErr) {
+			parseErr.Reason += "，按普通字符串处理"
+			lp.diagnostics = append(lp.diagnostics, parseErr)
+		}
+		node = &yaml.Node{Kind: yaml.ScalarNode, Value: text, Line: l.num, Column: column}
+		if flowDepth(text) > 0 {
+			// 直到输入结尾括号仍未闭合，通常是生成被截断
+			lp.truncated = append(lp.truncated, node)
+		}
+		return node
+	}
+	unclosed := false
+	if rest[0] == '"' || rest[0] == '\'' {
+		node, closed := lp.parseQuotedScalar(rest, l, column)
+		if node != nil {
+			return node
+		}
+		unclosed = !closed
+	}
+	node := &yaml.Node{Kind: yaml.ScalarNode, Value: lp.parsePlainScalar(rest, parentIndent), Line: l.num, Column: column}
+	if unclosed {
+		lp.truncated = append(lp.truncated, node)
+	}
+	return node
+}
+
+// parseQuotedScalar 解析可能跨行的引号标量，引号之后还有其他内容或引号未闭合时返回nil按普通标量处理，
+// closed表示是否找到了结束引号
+func (lp *lineParser) parseQuotedScalar(first string, l *parseLine, column int) (node *yaml.Node, closed bool) {
+	text := first
+	pos := lp.pos
+	end := quotedEnd(text, 0)
+	for end < 0 && pos < len(lp.lines) {
+		// 引号未闭合时继续读取后续行
+		from := len(text)
+		text += "\n" + strings.TrimSpace(lp.lines[pos].raw)
+		pos++
+		end = scanQuoteEnd(text, text[0], from)
+	}
+	if end < 0 {
+		lp.addDiagnostic(l.num, column, first, "引号未闭合，按普通字符串处理")
+		return nil, false
+	}
+	if strings.TrimSpace(text[end+1:]) != "" {
+		lp.addDiagnostic(l.num, column, first, "引号标量之后存在多余内容，按普通字符串处理")
+		return nil, true
+	}
+	for _, next := range lp.lines[lp.pos:pos] {
+		// 引号标量中以#开头的行是内容而不是注释
+		next.comment = ""
+	}
+	lp.pos = pos
+	node = &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Style: yaml.DoubleQuotedStyle, Value: unquoteScalar(text), Line: l.num, Column: column}
+	if text[0] == '\'' {
+		node.Style = yaml.SingleQuotedStyle
+	}
+	return node, true
+}
+
+// joinFlowLines 拼接跨多行的流式集合，直到括号闭合
+func (lp *lineParser) joinFlowLines(first string) string {
+	text := first
+	for flowDepth(text) > 0 {
+		l := lp.peek()
+		if l == nil {
+			break
+		}
+		text += " " + l.text
+		lp.pos++
+	}
+	return text
+}
+
+// parsePlainScalar 解析可能跨行的普通标量，缩进大于parentIndent且不是键值对的行视为续行
+func (lp *lineParser) parsePlainScalar(first string, parentIndent int) string {
+	value := first
+	for l := lp.peek(); l != nil; l = lp.peek() {
+		if l.indent <= parentIndent || isKeyLine(l.text) {
+			break
+		}
+		value += " " + l.text
+		lp.pos++
+	}
+	return value
+}
+
+// newAlias 创建指向已定义锚点的别名节点，锚点未定义时记录诊断信息并返回null
+func (lp *lineParser) newAlias(name string, line, column int) *yaml.Node {
+	target, ok := lp.anchors[name]
+	if !ok {
+		lp.addDiagnostic(line, column, "*"+name, "未定义的别名")
+		node := &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!null", Line: line, Column: column}
+		lp.placeholders = append(lp.placeholders, node)
+		return node
+	}
+	return &yaml.Node{Kind: yaml.AliasNode, Value: name, Alias: target, Line: line, Column: column}
+}
+
+// setAnchor 为节点设置锚点，节点解析完成后才登记，避免节点引用自身
+func (lp *lineParser) setAnchor(node *yaml.Node, anchor string) {
+	if node == nil || anchor == "" {
+		return
+	}
+	node.Anchor = anchor
+	lp.anchors[anchor] = node
+}
+
+// addDiagnostic 记录解析警告
+func (lp *lineParser) addDiagnostic(line, column int, snippet, reason string) {
+	lp.diagnostics = append(lp.diagnostics, &ParseError{Line: line, Column: column, Snippet: snippet, Reason: reason})
+}
+
+// headComment 返回并清除紧邻行l之上的整行注释（中间可以有空行），多行注释以换行连接。
+// 上一行内容比l缩进更深时（块在l之前结束），缩进比l深的注释属于之前的条目，留作尾注释
+func (lp *lineParser) headComment(l *parseLine) string {
+	indent := indentColumns(l.raw, lp.tabWidth)
+	start := l.num - 1
+	for start > 0 && lp.source[start-1].text == "" && !isDocumentMarker(lp.source[start-1].raw) {
+		start--
+	}
+	closing := start > 0 && lp.source[start-1].indent > indent
+	var comments []string
+	for _, prev := range lp.source[start : l.num-1] {
+		if prev.comment == "" || (closing && prev.indent > indent) {
+			continue
+		}
+		comments = append(comments, prev.comment)
+		prev.comment = ""
+	}
+	return strings.Join(comments, "\n")
+}
+
+// skipLine 跳过无法归属的行并记录警告
+func (lp *lineParser) skipLine(l *parseLine, reason string) {
+	lp.addDiagnostic(l.num, l.indent+1, l.text, reason)
+	lp.pos++
+}
+
+// skipRest 跳过剩余的所有行，每个非空行记录一条警告
+func (lp *lineParser) skipRest(reason string) {
+	for l := lp.peek(); l != nil; l = lp.peek() {
+		lp.skipLine(l, reason)
+	}
+}
+
+// newKeyNode 创建mapping的键节点，引号包裹的键去除引号
+func newKeyNode(key string, l *parseLine) *yaml.Node {
+	node := &yaml.Node{Kind: yaml.ScalarNode, Value: key, Line: l.num, Column: l.indent + 1}
+	if isQuotedScalar(key) {
+		node.Tag = "!!str"
+		node.Value = unquoteScalar(key)
+		node.Style = yaml.DoubleQuotedStyle
+		if key[0] == '\'' {
+			node.Style = yaml.SingleQuotedStyle
+		}
+	}
+	return node
+}
+
+// isSeqItem 判断内容是否为数组项
+func isSeqItem(text string) bool {
+	return text == "-" || strings.HasPrefix(text, "- ") || strings.HasPrefix(text, "-\t")
+}
+
+// isExplicitKey 判断内容是否为显式键（"? key"）
+func isExplicitKey(text string) bool {
+	return text == "?" || strings.HasPrefix(text, "? ") || strings.HasPrefix(text, "?\t")
+}
+
+// isExplicitValue 判断内容是否为显式键的值（": value"）
+func isExplicitValue(text string) bool {
+	return text == ":" || strings.HasPrefix(text, ": ") || strings.HasPrefix(text, ":\t")
+}
+
+// isPlainText 判断内容是否为普通文本，不是数组项、键值对，也不以引号、流式集合、块标量或锚点等指示符开头
+func isPlainText(text string) bool {
+	if isCollectionLine(text) {
+		return false
+	}
+	return !strings.ContainsAny(text[:1], "\"'[{|>&*!")
+}
+
+// isCollectionLine 判断内容（去除锚点后）是否开始一个数组或mapping
+func isCollectionLine(text string) bool {
+	_, inner := splitAnchor(text)
+	return isSeqItem(inner) || isKeyLine(inner) || isExplicitKey(inner)
+}
+
+// splitAnchor 拆分值开头的锚点（&name），返回锚点名和剩余内容
+func splitAnchor(value string) (string, string) {
+	if !strings.HasPrefix(value, "&") {
+		return "", value
+	}
+	end := strings.IndexAny(value, " \t")
+	if end < 0 {
+		end = len(value)
+	}
+	name := value[1:end]
+	if !isAnchorName(name) {
+		return "", value
+	}
+	return name, strings.TrimSpace(value[end:])
+}
+
+// parseAlias 判断值是否为别名（*name），返回别名名称
+func parseAlias(value string) (string, bool) {
+	if !strings.HasPrefix(value, "*") || !isAnchorName(value[1:]) {
+		return "", false
+	}
+	return value[1:], true
+}
+
+// isAnchorName 判断是否为合法的锚点名
+func isAnchorName(name string) bool {
+	return name != "" && !strings.ContainsAny(name, " \t,[]{}")
 }